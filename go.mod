@@ -3,9 +3,11 @@ module github.com/ChaseHampton/gofindag
 go 1.23.3
 
 require (
+	github.com/andybalholm/brotli v1.2.6
 	github.com/jmoiron/sqlx v1.4.0
 	github.com/microsoft/go-mssqldb v1.8.1
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/net v0.26.0
 )
 
 require (
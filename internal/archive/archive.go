@@ -0,0 +1,174 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const indexFile = "index.jsonl"
+
+// Record is one archived response. The body is stored once per distinct
+// content under Key; every fetch that returned it gets its own index entry.
+type Record struct {
+	Key        string        `json:"key"`
+	URL        string        `json:"url"`
+	StatusCode int           `json:"status"`
+	Headers    http.Header   `json:"headers"`
+	Duration   time.Duration `json:"duration"`
+	FetchedAt  time.Time     `json:"fetchedAt"`
+	Body       []byte        `json:"-"`
+}
+
+// Store is a content-addressed archive of raw responses on disk. Bodies are
+// gzipped under objects/ and named by their SHA-256, and index.jsonl holds
+// one line of metadata per fetch.
+type Store struct {
+	dir string
+
+	mu    sync.Mutex
+	index *os.File
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	index, err := os.OpenFile(filepath.Join(dir, indexFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive index: %w", err)
+	}
+	return &Store{dir: dir, index: index}, nil
+}
+
+// Key returns the key body is archived under.
+func Key(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Put archives rec and returns the key of its body.
+func (s *Store) Put(rec Record) (string, error) {
+	rec.Key = Key(rec.Body)
+	if rec.FetchedAt.IsZero() {
+		rec.FetchedAt = time.Now()
+	}
+	if err := s.writeObject(rec.Key, rec.Body); err != nil {
+		return "", err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal archive record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.index.Write(append(line, '\n')); err != nil {
+		return "", fmt.Errorf("failed to write archive index: %w", err)
+	}
+	return rec.Key, nil
+}
+
+func (s *Store) objectPath(key string) string {
+	return filepath.Join(s.dir, "objects", key[:2], key[2:]+".gz")
+}
+
+func (s *Store) writeObject(key string, body []byte) error {
+	path := s.objectPath(key)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write archive object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	_, err = zw.Write(body)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write archive object %s: %w", key, err)
+	}
+	// objects are immutable, so a concurrent writer of the same key renaming
+	// over this one is harmless
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write archive object %s: %w", key, err)
+	}
+	return nil
+}
+
+// Body returns the archived body stored under key.
+func (s *Store) Body(key string) ([]byte, error) {
+	if _, err := hex.DecodeString(key); err != nil || len(key) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid archive key %q", key)
+	}
+	f, err := os.Open(s.objectPath(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive object %s: %w", key, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive object %s: %w", key, err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive object %s: %w", key, err)
+	}
+	return body, nil
+}
+
+// Records returns the index entries in the order they were archived,
+// without their bodies.
+func (s *Store) Records() ([]Record, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+	var records []Record
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failed to parse archive index: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+	return records, nil
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.Close()
+}
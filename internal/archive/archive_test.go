@@ -0,0 +1,78 @@
+package archive_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_PutAndRead(t *testing.T) {
+	dir := t.TempDir()
+	store, err := archive.NewStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	body := []byte(`{"total":1,"memorials":[{"id":1}]}`)
+	key, err := store.Put(archive.Record{
+		URL:        "https://www.findagrave.com/memorial/search?page=1",
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Duration:   120 * time.Millisecond,
+		Body:       body,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, archive.Key(body), key)
+
+	got, err := store.Body(key)
+	require.NoError(t, err)
+	assert.Equal(t, body, got)
+
+	records, err := store.Records()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, key, records[0].Key)
+	assert.Equal(t, "https://www.findagrave.com/memorial/search?page=1", records[0].URL)
+	assert.Equal(t, http.StatusOK, records[0].StatusCode)
+	assert.Equal(t, "application/json", records[0].Headers.Get("Content-Type"))
+	assert.Equal(t, 120*time.Millisecond, records[0].Duration)
+	assert.False(t, records[0].FetchedAt.IsZero())
+}
+
+func TestStore_DeduplicatesBodies(t *testing.T) {
+	dir := t.TempDir()
+	store, err := archive.NewStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	body := []byte(`{"total":0,"memorials":[]}`)
+	first, err := store.Put(archive.Record{URL: "https://example.test/a", Body: body})
+	require.NoError(t, err)
+	second, err := store.Put(archive.Record{URL: "https://example.test/b", Body: body})
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	objects, err := filepath.Glob(filepath.Join(dir, "objects", "*", "*.gz"))
+	require.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	records, err := store.Records()
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestStore_BodyRejectsBadKeys(t *testing.T) {
+	store, err := archive.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.Body("../../etc/passwd")
+	assert.Error(t, err)
+	_, err = store.Body(archive.Key([]byte("missing")))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
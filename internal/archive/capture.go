@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// KeyHeader is set on captured responses to the key their body was archived
+// under.
+const KeyHeader = "X-Archive-Key"
+
+// Capture is client middleware that archives every response passing through
+// it in store. The body is read in full to archive it, and the response is
+// returned with KeyHeader set. A failure to archive is logged rather than
+// failing the request.
+func Capture(store *Store) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			resp.ContentLength = int64(len(body))
+
+			key, err := store.Put(Record{
+				URL:        req.URL.String(),
+				StatusCode: resp.StatusCode,
+				Headers:    resp.Header.Clone(),
+				Duration:   time.Since(start),
+				Body:       body,
+			})
+			if err != nil {
+				fmt.Println(fmt.Errorf("failed to archive response for %s: %w", req.URL, err))
+				return resp, nil
+			}
+			resp.Header.Set(KeyHeader, key)
+			return resp, nil
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
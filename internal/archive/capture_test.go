@@ -0,0 +1,41 @@
+package archive_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture_ArchivesAndTagsResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total":5}`))
+	}))
+	defer srv.Close()
+
+	store, err := archive.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer store.Close()
+
+	httpClient := &http.Client{Transport: archive.Capture(store)(http.DefaultTransport)}
+	resp, err := httpClient.Get(srv.URL + "/memorial/search?page=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"total":5}`, string(body))
+	key := resp.Header.Get(archive.KeyHeader)
+	assert.Equal(t, archive.Key(body), key)
+
+	records, err := store.Records()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, srv.URL+"/memorial/search?page=1", records[0].URL)
+	assert.Equal(t, "application/json", records[0].Headers.Get("Content-Type"))
+}
@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Replay is an http.RoundTripper that answers requests from the archive
+// instead of the network. Each URL is served the most recently archived
+// response for it; URLs that were never archived get a 404.
+type Replay struct {
+	store *Store
+	byURL map[string]Record
+}
+
+func NewReplay(store *Store) (*Replay, error) {
+	records, err := store.Records()
+	if err != nil {
+		return nil, err
+	}
+	byURL := make(map[string]Record, len(records))
+	for _, rec := range records {
+		byURL[canonicalURL(rec.URL)] = rec
+	}
+	return &Replay{store: store, byURL: byURL}, nil
+}
+
+// Len returns how many distinct URLs can be replayed.
+func (r *Replay) Len() int {
+	return len(r.byURL)
+}
+
+func (r *Replay) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	rec, ok := r.byURL[canonicalURL(req.URL.String())]
+	if !ok {
+		body := []byte(fmt.Sprintf("no archived response for %s", req.URL))
+		return newResponse(req, http.StatusNotFound, http.Header{"Content-Type": {"text/plain"}}, body), nil
+	}
+	body, err := r.store.Body(rec.Key)
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, rec.StatusCode, rec.Headers.Clone(), body), nil
+}
+
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	// the archived body is already decoded
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// canonicalURL orders the query parameters of raw so the same search matches
+// however its URL was built.
+func canonicalURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.RawQuery = u.Query().Encode()
+	u.Fragment = ""
+	return u.String()
+}
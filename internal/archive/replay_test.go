@@ -0,0 +1,56 @@
+package archive_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay_ServesLatestArchivedResponse(t *testing.T) {
+	store, err := archive.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.Put(archive.Record{URL: "https://www.findagrave.com/memorial/search?page=1&lastName=A*", StatusCode: http.StatusOK, Body: []byte(`{"total":1}`)})
+	require.NoError(t, err)
+	_, err = store.Put(archive.Record{
+		URL:        "https://www.findagrave.com/memorial/search?page=1&lastName=A*",
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+		Body:       []byte(`{"total":2}`),
+	})
+	require.NoError(t, err)
+
+	replay, err := archive.NewReplay(store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, replay.Len())
+	httpClient := &http.Client{Transport: replay}
+
+	// parameter order does not matter
+	resp, err := httpClient.Get("https://www.findagrave.com/memorial/search?lastName=A%2A&page=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"total":2}`, string(body))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+}
+
+func TestReplay_MissingURLIsNotFound(t *testing.T) {
+	store, err := archive.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer store.Close()
+
+	replay, err := archive.NewReplay(store)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: replay}).Get("https://www.findagrave.com/memorial/search?page=9")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is sent with every request. Setting it ourselves turns off
+// the transport's own gzip handling, so bodies are decoded by decodeBody.
+const acceptEncoding = "gzip, br"
+
+// ErrBodyTooLarge is returned when a decoded response body is longer than
+// the client's maximum.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// decodeBody returns resp's body decompressed according to its
+// Content-Encoding and cut off with ErrBodyTooLarge after max bytes; a max
+// of 0 is unlimited. The encoding headers are removed since they no longer
+// describe the body.
+func decodeBody(resp *http.Response, max int64) (io.ReadCloser, error) {
+	var r io.Reader
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		r = resp.Body
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip response: %w", err)
+		}
+		r = zr
+	case "br":
+		r = brotli.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if encoding != "" {
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+	}
+	return &boundedBody{r: r, body: resp.Body, max: max, remaining: max}, nil
+}
+
+type boundedBody struct {
+	r         io.Reader
+	body      io.Closer
+	max       int64
+	remaining int64
+}
+
+func (b *boundedBody) Read(p []byte) (int, error) {
+	if b.max <= 0 {
+		return b.r.Read(p)
+	}
+	// read one byte past the limit to tell a body of exactly max bytes from
+	// a longer one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n - 1, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, b.max)
+	}
+	return n, err
+}
+
+func (b *boundedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,82 @@
+package client_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compressed(t *testing.T, encoding, body string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	}
+	_, err := w.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestClient_DecodesContentEncoding(t *testing.T) {
+	const payload = `{"total":1,"records":[]}`
+	for _, encoding := range []string{"gzip", "br"} {
+		t.Run(encoding, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Contains(t, r.Header.Get("Accept-Encoding"), encoding)
+				w.Header().Set("Content-Encoding", encoding)
+				w.Write(compressed(t, encoding, payload))
+			}))
+			defer srv.Close()
+
+			c, err := client.NewPageClient(testHTTPConfig(), nil)
+			require.NoError(t, err)
+			resp, err := c.Get(context.Background(), srv.URL)
+			require.NoError(t, err)
+			assert.Equal(t, payload, string(resp.Body))
+			assert.Empty(t, resp.Headers.Get("Content-Encoding"))
+		})
+	}
+}
+
+func TestClient_MaxBodyBytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// compresses to far less than the limit, which applies once decoded
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed(t, "gzip", strings.Repeat("a", 4096)))
+	}))
+	defer srv.Close()
+
+	cfg := testHTTPConfig()
+	cfg.MaxBodyBytes = 4096
+	c, err := client.NewPageClient(cfg, nil)
+	require.NoError(t, err)
+	resp, err := c.Get(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Len(t, resp.Body, 4096)
+
+	cfg.MaxBodyBytes = 4095
+	c, err = client.NewPageClient(cfg, nil)
+	require.NoError(t, err)
+	_, err = c.Get(context.Background(), srv.URL)
+	assert.ErrorIs(t, err, client.ErrBodyTooLarge)
+
+	_, body, err := c.Stream(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer body.Close()
+	_, err = io.Copy(io.Discard, body)
+	assert.ErrorIs(t, err, client.ErrBodyTooLarge)
+}
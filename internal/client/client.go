@@ -2,98 +2,232 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"time"
 
+	"github.com/ChaseHampton/gofindag/internal/archive"
 	"github.com/ChaseHampton/gofindag/internal/config"
 )
 
 type Client struct {
 	httpClient *http.Client
-	userAgent  string
+	cookies    *CookieJar
+	metrics    *Metrics
 }
 
 type Response struct {
 	StatusCode int
 	Body       []byte
 	Headers    http.Header
-	Duration   time.Duration
+	// Duration is how long the response headers took to arrive once the
+	// request was sent.
+	Duration time.Duration
+	// Wait is how long the rate limiter held the request before it was sent.
+	Wait time.Duration
 }
 
-func NewClient(cfg *config.HTTPConfig) *Client {
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:    cfg.MaxIdleConns,
-				MaxConnsPerHost: cfg.MaxConnsPerHost,
-				IdleConnTimeout: cfg.IdleConnTimeout,
-			},
-		},
-		userAgent: cfg.UserAgent,
+type sentAtKey struct{}
+
+// markSent records on ctx, if openRequest is timing it, when the request
+// left the rate limiter. Only the first call counts, so redirects add to
+// the request's latency rather than restarting it.
+func markSent(ctx context.Context) {
+	if sent, ok := ctx.Value(sentAtKey{}).(*time.Time); ok && sent.IsZero() {
+		*sent = time.Now()
 	}
 }
 
-func NewPageClient(cfg *config.HTTPConfig) *Client {
-	cookiejar := http.CookieJar(nil)
-	transport := &http.Transport{
-		MaxIdleConns:      cfg.MaxIdleConns,
-		MaxConnsPerHost:   cfg.MaxConnsPerHost,
-		IdleConnTimeout:   cfg.IdleConnTimeout,
-		DisableKeepAlives: true,
+// NewClient returns a client with the middleware cfg configures followed by
+// extra, which sits just outside response decoding. Requests are paced by
+// limiters, or not at all if it is nil.
+func NewClient(cfg *config.HTTPConfig, limiters *HostLimiters, extra ...Middleware) (*Client, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{}
+	c.httpClient = &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: c.chain(cfg, transport, limiters, extra),
+	}
+	return c, nil
+}
+
+// NewPageClient builds the client used to fetch search pages. It is meant to
+// be created once and shared by every worker so connections are pooled and
+// reused rather than re-established for each page. An invalid proxy
+// configuration is reported here rather than on the first request.
+//
+// Requests are paced by limiters, or not at all if it is nil. Clients given
+// the same limiters share each host's pace.
+//
+// Cookies are kept for the life of the client, and between runs when
+// cfg.CookieFile is set, so the crawl looks like one browser session.
+func NewPageClient(cfg *config.HTTPConfig, limiters *HostLimiters, extra ...Middleware) (*Client, error) {
+	cookies, err := NewCookieJar(cfg.CookieFile)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{cookies: cookies}
+	c.httpClient = &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: c.chain(cfg, transport, limiters, extra),
+		Jar:       cookies,
+	}
+	return c, nil
+}
+
+// NewReplayClient returns a client that serves every request from store
+// rather than the network, so archived crawls can be processed again.
+// Requests are not paced since no site is contacted.
+func NewReplayClient(cfg *config.HTTPConfig, store *archive.Store, extra ...Middleware) (*Client, error) {
+	replay, err := archive.NewReplay(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archive for replay: %w", err)
+	}
+	c := &Client{}
+	c.httpClient = &http.Client{Transport: c.chain(cfg, replay, nil, extra)}
+	return c, nil
+}
+
+// chain wraps base in the middleware cfg configures, then extra, then
+// response decoding. Pacing is left out when limiters is nil.
+func (c *Client) chain(cfg *config.HTTPConfig, base http.RoundTripper, limiters *HostLimiters, extra []Middleware) http.RoundTripper {
+	headers := http.Header{}
+	headers.Set("User-Agent", cfg.UserAgent)
+	for name, value := range cfg.Headers {
+		headers.Set(name, value)
+	}
+	middlewares := []Middleware{Headers(headers)}
+	if limiters != nil {
+		middlewares = append(middlewares, RateLimit(limiters))
+	}
+	if cfg.LogRequests {
+		middlewares = append(middlewares, Logging(func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		}))
+	}
+	if cfg.Metrics {
+		c.metrics = NewMetrics()
+		middlewares = append(middlewares, c.metrics.Middleware())
+	}
+	middlewares = append(middlewares, extra...)
+	middlewares = append(middlewares, Decompress(cfg.MaxBodyBytes))
+	return Chain(base, middlewares...)
+}
+
+// Metrics returns the client's request metrics, or nil if cfg.Metrics was
+// not set.
+func (c *Client) Metrics() *Metrics {
+	return c.metrics
+}
+
+// WarmUp loads url, normally the search landing page, once so the session
+// has whatever cookies the site sets there before the JSON requests start.
+func (c *Client) WarmUp(ctx context.Context, url string) error {
+	resp, err := c.Get(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to warm up session: %w", err)
 	}
-	if *cfg.ProxyKey != "" && *cfg.ProxyUrl != "" {
-		proxyURL, err := url.Parse(*cfg.ProxyUrl)
-		if err == nil {
-			proxyURL.User = url.User(*cfg.ProxyKey)
-			transport.Proxy = http.ProxyURL(proxyURL)
-		}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to warm up session: %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// SaveCookies persists the session's cookies if the client has a cookie
+// file.
+func (c *Client) SaveCookies() error {
+	if c.cookies == nil {
+		return nil
+	}
+	return c.cookies.Save()
+}
 
+// newTransport returns a keep-alive transport sized from cfg that connects
+// through the configured proxy, if any.
+func newTransport(cfg *config.HTTPConfig) (*http.Transport, error) {
+	proxy, err := ProxyFunc(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy configuration: %w", err)
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
 	}
-	return &Client{
-		httpClient: &http.Client{
-			Timeout:   cfg.Timeout,
-			Transport: transport,
-			Jar:       cookiejar,
-		},
-		userAgent: cfg.UserAgent,
+	if cfg.DisableHTTP2 {
+		// a non-nil, empty map stops the transport negotiating h2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
 	}
+	return transport, nil
+}
+
+// CloseIdleConnections closes pooled connections that are not in use.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
 }
 
 func (c *Client) Get(ctx context.Context, url string) (*Response, error) {
 	return c.makeRequest(ctx, "GET", url, nil)
 }
 
+// Stream is Get without reading the body, so it can be decoded as it
+// arrives. The returned Response has no Body; the caller must close the
+// reader.
+func (c *Client) Stream(ctx context.Context, url string) (*Response, io.ReadCloser, error) {
+	return c.openRequest(ctx, "GET", url, nil)
+}
+
 func (c *Client) makeRequest(ctx context.Context, method, url string, body io.Reader) (*Response, error) {
-	start := time.Now()
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	response, responseBody, err := c.openRequest(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	defer responseBody.Close()
 
-	req.Header.Set("User-Agent", c.userAgent)
-
-	resp, err := c.httpClient.Do(req)
+	response.Body, err = io.ReadAll(responseBody)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return response, nil
+}
 
-	duration := time.Since(start)
+func (c *Client) openRequest(ctx context.Context, method, url string, body io.Reader) (*Response, io.ReadCloser, error) {
+	start := time.Now()
+	var sent time.Time
+	ctx = context.WithValue(ctx, sentAtKey{}, &sent)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	responseBody, err := io.ReadAll(resp.Body)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	if sent.IsZero() {
+		sent = start
+	}
 	response := &Response{
 		StatusCode: resp.StatusCode,
 		Headers:    resp.Header,
-		Body:       responseBody,
-		Duration:   duration,
+		Duration:   time.Since(sent),
+		Wait:       sent.Sub(start),
 	}
 
-	return response, nil
+	return response, resp.Body, nil
 }
@@ -0,0 +1,127 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/archive"
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/ChaseHampton/gofindag/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHTTPConfig() *config.HTTPConfig {
+	return &config.HTTPConfig{
+		Timeout:             5 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		MaxConnsPerHost:     10,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		UserAgent:           "gofindag-test",
+	}
+}
+
+// newCountingServer returns a server that reports how many connections
+// clients have opened to it.
+func newCountingServer(t testing.TB) (*httptest.Server, *atomic.Int64) {
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total":0,"memorials":[]}`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv, &conns
+}
+
+func TestPageClient_ReusesConnections(t *testing.T) {
+	srv, conns := newCountingServer(t)
+	c, err := client.NewPageClient(testHTTPConfig(), nil)
+	require.NoError(t, err)
+	defer c.CloseIdleConnections()
+
+	for range 10 {
+		resp, err := c.Get(context.Background(), srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	assert.Equal(t, int64(1), conns.Load())
+}
+
+func TestPageClient_DurationExcludesRateLimitWait(t *testing.T) {
+	srv, _ := newCountingServer(t)
+	c, err := client.NewPageClient(testHTTPConfig(), client.NewHostLimiters(4, 1))
+	require.NoError(t, err)
+	defer c.CloseIdleConnections()
+
+	_, err = c.Get(context.Background(), srv.URL)
+	require.NoError(t, err)
+	resp, err := c.Get(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, resp.Wait, 150*time.Millisecond)
+	assert.Less(t, resp.Duration, 150*time.Millisecond)
+}
+
+func BenchmarkPageClient_Pooled(b *testing.B) {
+	srv, _ := newCountingServer(b)
+	c, err := client.NewPageClient(testHTTPConfig(), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.CloseIdleConnections()
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := c.Get(context.Background(), srv.URL); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPageClient_PerRequest builds a client for every request, as pages
+// used to be fetched, so each one opens a new connection.
+func BenchmarkPageClient_PerRequest(b *testing.B) {
+	srv, _ := newCountingServer(b)
+	cfg := testHTTPConfig()
+
+	b.ResetTimer()
+	for range b.N {
+		c, err := client.NewPageClient(cfg, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := c.Get(context.Background(), srv.URL); err != nil {
+			b.Fatal(err)
+		}
+		c.CloseIdleConnections()
+	}
+}
+
+func TestReplayClient_ServesArchive(t *testing.T) {
+	store, err := archive.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer store.Close()
+	_, err = store.Put(archive.Record{URL: "https://www.findagrave.com/memorial/search?page=1", StatusCode: http.StatusOK, Body: []byte(`{"total":0}`)})
+	require.NoError(t, err)
+
+	c, err := client.NewReplayClient(testHTTPConfig(), store)
+	require.NoError(t, err)
+	resp, err := c.Get(context.Background(), "https://www.findagrave.com/memorial/search?page=1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"total":0}`, string(resp.Body))
+}
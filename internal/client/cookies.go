@@ -0,0 +1,142 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CookieJar is a cookie jar that can be saved to and restored from a file,
+// so a crawl carries its session over between runs. With no path it is an
+// ordinary in-memory jar.
+type CookieJar struct {
+	jar  *cookiejar.Jar
+	path string
+
+	mu sync.Mutex
+	// cookies holds every cookie set, keyed by the origin that set it and
+	// then by domain, path and name, so they can be replayed into a new jar.
+	cookies map[string]map[string]*http.Cookie
+}
+
+type savedCookies struct {
+	URL     string         `json:"url"`
+	Cookies []*http.Cookie `json:"cookies"`
+}
+
+// NewCookieJar returns a jar persisted at path, loading any cookies already
+// saved there.
+func NewCookieJar(path string) (*CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	cj := &CookieJar{jar: jar, path: path, cookies: map[string]map[string]*http.Cookie{}}
+	if path == "" {
+		return cj, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cj, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	var saved []savedCookies
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse cookie file %s: %w", path, err)
+	}
+	for _, s := range saved {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cookie file %s: %w", path, err)
+		}
+		cj.SetCookies(u, s.Cookies)
+	}
+	return cj, nil
+}
+
+func (cj *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	cj.jar.SetCookies(u, cookies)
+
+	cj.mu.Lock()
+	defer cj.mu.Unlock()
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+	set, ok := cj.cookies[origin]
+	if !ok {
+		set = map[string]*http.Cookie{}
+		cj.cookies[origin] = set
+	}
+	now := time.Now()
+	for _, c := range cookies {
+		c := *c
+		// Max-Age is relative to when the cookie was set, so it is stored as
+		// an absolute expiry for the next run
+		if c.MaxAge > 0 {
+			c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			c.MaxAge = 0
+		}
+		key := c.Domain + ";" + c.Path + ";" + c.Name
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && !c.Expires.After(now)) {
+			delete(set, key)
+			continue
+		}
+		set[key] = &c
+	}
+}
+
+func (cj *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return cj.jar.Cookies(u)
+}
+
+// Save writes the jar's unexpired cookies to its file. Session cookies are
+// kept too, since the next run continues the same session.
+func (cj *CookieJar) Save() error {
+	if cj.path == "" {
+		return nil
+	}
+	cj.mu.Lock()
+	now := time.Now()
+	var saved []savedCookies
+	for origin, set := range cj.cookies {
+		s := savedCookies{URL: origin}
+		for _, c := range set {
+			if c.Expires.IsZero() || c.Expires.After(now) {
+				s.Cookies = append(s.Cookies, c)
+			}
+		}
+		if len(s.Cookies) > 0 {
+			saved = append(saved, s)
+		}
+	}
+	cj.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cookies: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cj.path), filepath.Base(cj.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save cookies: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save cookies: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save cookies: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), cj.path); err != nil {
+		return fmt.Errorf("failed to save cookies: %w", err)
+	}
+	return nil
+}
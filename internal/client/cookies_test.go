@@ -0,0 +1,76 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieJar_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	site, _ := url.Parse("https://www.findagrave.com/memorial/search")
+
+	jar, err := client.NewCookieJar(path)
+	require.NoError(t, err)
+	jar.SetCookies(site, []*http.Cookie{
+		{Name: "session", Value: "abc", Path: "/"},
+		{Name: "prefs", Value: "dark", Path: "/", MaxAge: 3600},
+		{Name: "stale", Value: "old", Path: "/", Expires: time.Now().Add(-time.Hour)},
+	})
+	require.NoError(t, jar.Save())
+
+	reloaded, err := client.NewCookieJar(path)
+	require.NoError(t, err)
+	values := map[string]string{}
+	for _, c := range reloaded.Cookies(site) {
+		values[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{"session": "abc", "prefs": "dark"}, values)
+}
+
+func TestCookieJar_MissingFileStartsEmpty(t *testing.T) {
+	jar, err := client.NewCookieJar(filepath.Join(t.TempDir(), "none.json"))
+	require.NoError(t, err)
+	site, _ := url.Parse("https://www.findagrave.com/")
+	assert.Empty(t, jar.Cookies(site))
+}
+
+func TestPageClient_WarmUpStartsSession(t *testing.T) {
+	var searchCookie string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ajax") == "" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "warm", Path: "/"})
+			return
+		}
+		if c, err := r.Cookie("session"); err == nil {
+			searchCookie = c.Value
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testHTTPConfig()
+	cfg.CookieFile = filepath.Join(t.TempDir(), "cookies.json")
+	c, err := client.NewPageClient(cfg, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, c.WarmUp(context.Background(), srv.URL+"/memorial/search"))
+	_, err = c.Get(context.Background(), srv.URL+"/memorial/search?ajax=true")
+	require.NoError(t, err)
+	assert.Equal(t, "warm", searchCookie)
+
+	require.NoError(t, c.SaveCookies())
+	searchCookie = ""
+	next, err := client.NewPageClient(cfg, nil)
+	require.NoError(t, err)
+	_, err = next.Get(context.Background(), srv.URL+"/memorial/search?ajax=true")
+	require.NoError(t, err)
+	assert.Equal(t, "warm", searchCookie)
+}
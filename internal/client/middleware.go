@@ -0,0 +1,195 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware wraps the transport a client sends its requests through, so
+// concerns such as logging or pacing can be added without changing the
+// client itself.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps rt in middlewares. The first middleware is the outermost, so
+// it sees each request first and each response last.
+func Chain(rt http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+// Headers sets header on every request, replacing any values already set.
+func Headers(header http.Header) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// a RoundTripper must not modify the request it is given
+			req = req.Clone(req.Context())
+			for name, values := range header {
+				req.Header[http.CanonicalHeaderKey(name)] = values
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RateLimit paces requests to each host with limiters, slowing down when a
+// host answers 429 or 503 and speeding back up as it recovers.
+func RateLimit(limiters *HostLimiters) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			limiter := limiters.For(req.URL.String())
+			if limiter == nil {
+				return next.RoundTrip(req)
+			}
+			if err := limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+			markSent(req.Context())
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			switch resp.StatusCode {
+			case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+				limiter.Backoff()
+			default:
+				limiter.Recover()
+			}
+			return resp, nil
+		})
+	}
+}
+
+// Logging reports every request, its status and how long it took to logf.
+func Logging(logf func(format string, args ...any)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				logf("%s %s failed after %v: %v", req.Method, req.URL, time.Since(start), err)
+				return nil, err
+			}
+			logf("%s %s %d in %v", req.Method, req.URL, resp.StatusCode, time.Since(start))
+			return resp, nil
+		})
+	}
+}
+
+// Inject answers a request with whatever fault returns instead of sending
+// it, unless fault returns neither a response nor an error. It lets tests
+// exercise failure handling against a real client.
+func Inject(fault func(*http.Request) (*http.Response, error)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if resp, err := fault(req); resp != nil || err != nil {
+				return resp, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Decompress asks for compressed responses and decodes them, cutting bodies
+// off with ErrBodyTooLarge after maxBodyBytes; 0 is unlimited. Middleware
+// outside it sees decoded bodies.
+func Decompress(maxBodyBytes int64) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Accept-Encoding") == "" {
+				req = req.Clone(req.Context())
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			body, err := decodeBody(resp, maxBodyBytes)
+			if err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+			resp.Body = body
+			return resp, nil
+		})
+	}
+}
+
+// Metrics counts the requests a client makes and how they turned out.
+type Metrics struct {
+	mu       sync.Mutex
+	requests int
+	errors   int
+	statuses map[int]int
+	latency  time.Duration
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{statuses: map[int]int{}}
+}
+
+func (m *Metrics) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.requests++
+			m.latency += time.Since(start)
+			if err != nil {
+				m.errors++
+				return nil, err
+			}
+			m.statuses[resp.StatusCode]++
+			return resp, nil
+		})
+	}
+}
+
+// MetricsSnapshot is the state of a Metrics at one point in time.
+type MetricsSnapshot struct {
+	Requests    int
+	Errors      int
+	Statuses    map[int]int
+	MeanLatency time.Duration
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := MetricsSnapshot{Requests: m.requests, Errors: m.errors, Statuses: make(map[int]int, len(m.statuses))}
+	for status, n := range m.statuses {
+		s.Statuses[status] = n
+	}
+	if m.requests > 0 {
+		s.MeanLatency = m.latency / time.Duration(m.requests)
+	}
+	return s
+}
+
+func (s MetricsSnapshot) String() string {
+	statuses := make([]int, 0, len(s.Statuses))
+	for status := range s.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	counts := make([]string, len(statuses))
+	for i, status := range statuses {
+		counts[i] = fmt.Sprintf("%d=%d", status, s.Statuses[status])
+	}
+	return fmt.Sprintf("%d requests, %d errors, mean latency %v, statuses [%s]",
+		s.Requests, s.Errors, s.MeanLatency, strings.Join(counts, " "))
+}
@@ -0,0 +1,121 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func respond(status int, body string) client.RoundTripperFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	}
+}
+
+func TestChain_FirstMiddlewareIsOutermost(t *testing.T) {
+	var order []string
+	trace := func(name string) client.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" in")
+				resp, err := next.RoundTrip(req)
+				order = append(order, name+" out")
+				return resp, err
+			})
+		}
+	}
+
+	rt := client.Chain(respond(http.StatusOK, ""), trace("a"), trace("b"))
+	_, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.test/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a in", "b in", "b out", "a out"}, order)
+}
+
+func TestHeaders_DoesNotModifyTheRequest(t *testing.T) {
+	var got http.Header
+	rt := client.Chain(client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header
+		return respond(http.StatusOK, "")(req)
+	}), client.Headers(http.Header{"X-Test": {"1"}}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.test/", nil)
+	_, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "1", got.Get("X-Test"))
+	assert.Empty(t, req.Header.Get("X-Test"))
+}
+
+func TestLogging(t *testing.T) {
+	var lines []string
+	logf := func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+
+	rt := client.Chain(respond(http.StatusTeapot, ""), client.Logging(logf))
+	_, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.test/a", nil))
+	require.NoError(t, err)
+
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "GET http://example.test/a 418")
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := client.NewMetrics()
+	calls := 0
+	rt := client.Chain(client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 3 {
+			return nil, errors.New("connection reset")
+		}
+		return respond(http.StatusOK, "")(req)
+	}), metrics.Middleware())
+
+	for range 3 {
+		rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.test/", nil))
+	}
+	snap := metrics.Snapshot()
+	assert.Equal(t, 3, snap.Requests)
+	assert.Equal(t, 1, snap.Errors)
+	assert.Equal(t, map[int]int{http.StatusOK: 2}, snap.Statuses)
+	assert.Contains(t, snap.String(), "200=2")
+}
+
+func TestPageClient_ConfiguredAndExtraMiddleware(t *testing.T) {
+	var gotUA, gotLang string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA, gotLang = r.UserAgent(), r.Header.Get("Accept-Language")
+	}))
+	defer srv.Close()
+
+	cfg := testHTTPConfig()
+	cfg.Headers = map[string]string{"Accept-Language": "en-US"}
+	cfg.Metrics = true
+	fault := client.Inject(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/fail" {
+			return respond(http.StatusServiceUnavailable, "injected")(req)
+		}
+		return nil, nil
+	})
+	c, err := client.NewPageClient(cfg, nil, fault)
+	require.NoError(t, err)
+
+	resp, err := c.Get(context.Background(), srv.URL+"/ok")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "gofindag-test", gotUA)
+	assert.Equal(t, "en-US", gotLang)
+
+	resp, err = c.Get(context.Background(), srv.URL+"/fail")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "injected", string(resp.Body))
+
+	assert.Equal(t, 2, c.Metrics().Snapshot().Requests)
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ChaseHampton/gofindag/internal/config"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyFunc returns the transport proxy function described by cfg, or nil
+// when no proxy is configured. PROXY_URL may use the http, https or socks5
+// scheme; PROXY_KEY and PROXY_PASSWORD override any credentials in it.
+// Hosts matched by NoProxy, which follows the NO_PROXY conventions of
+// net/http, are connected to directly, as are localhost and loopback
+// addresses.
+func ProxyFunc(cfg *config.HTTPConfig) (func(*http.Request) (*url.URL, error), error) {
+	if cfg.ProxyUrl == nil || *cfg.ProxyUrl == "" {
+		if cfg.ProxyKey != nil || cfg.ProxyPassword != nil {
+			return nil, fmt.Errorf("PROXY_KEY and PROXY_PASSWORD require PROXY_URL")
+		}
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(*cfg.ProxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PROXY_URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("PROXY_URL must use the http, https or socks5 scheme, got %q", *cfg.ProxyUrl)
+	}
+	if proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("PROXY_URL %q has no host", *cfg.ProxyUrl)
+	}
+	if cfg.ProxyKey != nil {
+		if cfg.ProxyPassword != nil {
+			proxyURL.User = url.UserPassword(*cfg.ProxyKey, *cfg.ProxyPassword)
+		} else {
+			proxyURL.User = url.User(*cfg.ProxyKey)
+		}
+	} else if cfg.ProxyPassword != nil {
+		return nil, fmt.Errorf("PROXY_PASSWORD requires PROXY_KEY")
+	}
+
+	// httpproxy applies NO_PROXY the way the standard library does for the
+	// environment variables
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    cfg.NoProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that paces requests to a single host. Its
+// rate halves whenever the host signals overload and creeps back up to the
+// configured rate as requests succeed again.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	maxRate float64
+	minRate float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:    rps,
+		maxRate: rps,
+		minRate: rps / 16,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	l.refill(time.Now())
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// Backoff halves the rate after the host responds with 429 or 503.
+func (l *RateLimiter) Backoff() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate /= 2
+	if l.rate < l.minRate {
+		l.rate = l.minRate
+	}
+}
+
+// Recover nudges the rate back towards the configured rate after a
+// successful response.
+func (l *RateLimiter) Recover() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate == l.maxRate {
+		return
+	}
+	l.refill(time.Now())
+	l.rate += l.maxRate / 20
+	if l.rate > l.maxRate {
+		l.rate = l.maxRate
+	}
+}
+
+// Rate is the number of requests per second currently allowed.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// HostLimiters hands out one RateLimiter per host so every client sending
+// requests to that host shares its pace.
+type HostLimiters struct {
+	mu       sync.Mutex
+	rps      float64
+	burst    int
+	limiters map[string]*RateLimiter
+}
+
+// NewHostLimiters returns limiters allowing rps requests per second with the
+// given burst to each host. An rps of 0 or less disables rate limiting.
+func NewHostLimiters(rps float64, burst int) *HostLimiters {
+	return &HostLimiters{
+		rps:      rps,
+		burst:    burst,
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+// For returns the limiter for the host of rawURL, or nil if rate limiting is
+// disabled.
+func (h *HostLimiters) For(rawURL string) *RateLimiter {
+	if h == nil || h.rps <= 0 {
+		return nil
+	}
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	limiter, ok := h.limiters[host]
+	if !ok {
+		limiter = NewRateLimiter(h.rps, h.burst)
+		h.limiters[host] = limiter
+	}
+	return limiter
+}
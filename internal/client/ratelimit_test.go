@@ -0,0 +1,64 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_BurstThenPaced(t *testing.T) {
+	limiter := client.NewRateLimiter(50, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 4 {
+		require.NoError(t, limiter.Wait(ctx))
+	}
+	elapsed := time.Since(start)
+
+	// two requests ride the burst, the next two wait 20ms each
+	assert.GreaterOrEqual(t, elapsed, 35*time.Millisecond)
+	assert.Less(t, elapsed, 500*time.Millisecond)
+}
+
+func TestRateLimiter_WaitHonoursContext(t *testing.T) {
+	limiter := client.NewRateLimiter(0.1, 1)
+	require.NoError(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestRateLimiter_BackoffAndRecover(t *testing.T) {
+	limiter := client.NewRateLimiter(8, 1)
+
+	limiter.Backoff()
+	assert.Equal(t, 4.0, limiter.Rate())
+	for range 10 {
+		limiter.Backoff()
+	}
+	assert.Equal(t, 0.5, limiter.Rate(), "rate should not drop below a sixteenth of the configured rate")
+
+	for range 100 {
+		limiter.Recover()
+	}
+	assert.Equal(t, 8.0, limiter.Rate())
+}
+
+func TestHostLimiters_SharedPerHost(t *testing.T) {
+	limiters := client.NewHostLimiters(5, 1)
+
+	a := limiters.For("https://www.findagrave.com/memorial/search?page=1")
+	b := limiters.For("https://www.findagrave.com/cemetery/1/memorial-search")
+	other := limiters.For("https://example.com/")
+
+	assert.Same(t, a, b)
+	assert.NotSame(t, a, other)
+	assert.Nil(t, client.NewHostLimiters(0, 1).For("https://example.com/"))
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,6 +22,34 @@ type HTTPConfig struct {
 	IdleConnTimeout time.Duration
 	ProxyKey        *string
 	ProxyUrl        *string
+	// RateLimitRPS and RateLimitBurst pace requests to each host across
+	// every worker; an RPS of 0 disables the limit.
+	RateLimitRPS   float64
+	RateLimitBurst int
+	// MaxIdleConnsPerHost is how many connections to each host are kept open
+	// for reuse between requests; page requests all go to one host, so it
+	// should be at least the worker count. HTTP/2 is negotiated over TLS
+	// unless DisableHTTP2 is set.
+	MaxIdleConnsPerHost int
+	TLSHandshakeTimeout time.Duration
+	DisableHTTP2        bool
+	// ProxyKey and ProxyPassword are the proxy credentials. NoProxy lists
+	// hosts to reach directly, in the NO_PROXY format.
+	ProxyPassword *string
+	NoProxy       string
+	// CookieFile persists the page client's cookies between runs; empty
+	// keeps them in memory. SessionWarmup loads the search landing page
+	// before the first search.
+	CookieFile    string
+	SessionWarmup bool
+	// MaxBodyBytes is the largest decoded response body accepted; 0 is
+	// unlimited.
+	MaxBodyBytes int64
+	// Headers are sent with every request, after UserAgent so they can
+	// override it. LogRequests logs each request and Metrics counts them.
+	Headers     map[string]string
+	LogRequests bool
+	Metrics     bool
 }
 
 type ProcessorConfig struct {
@@ -32,6 +62,57 @@ type ProcessorConfig struct {
 	BatchSize      int
 	FlushTimeout   time.Duration
 	ChannelSize    int
+	// RetryMaxDelay caps the exponential backoff between attempts, and
+	// RetryAfterMax is the longest Retry-After that is waited out rather
+	// than failing the page.
+	RetryMaxDelay time.Duration
+	RetryAfterMax time.Duration
+	// MaxPartitionDepth bounds how many times a search that exceeds the page
+	// cap is split into narrower child searches.
+	MaxPartitionDepth int
+	// MaxPrefixLength is the longest name wildcard prefix the partitioner
+	// will generate (e.g. 3 allows "SMI*" but not "SMIT*").
+	MaxPrefixLength int
+	// DriftTolerance is how many records a search total may move by while
+	// its pages are fetched before the collection's coverage is suspect.
+	DriftTolerance int
+	// IncrementalOrderBy is the site's orderby value that lists the most
+	// recently changed memorials first. When set, incremental searches are
+	// sorted by it and stop at the first page older than the watermark;
+	// otherwise every page is fetched and only newer records are kept.
+	IncrementalOrderBy string
+	// CollectionMaxPages, CollectionMaxRecords and CollectionDeadline are
+	// the default per-collection budgets; 0 is unlimited. MaxPages, the
+	// site's page cap, always applies.
+	CollectionMaxPages   int
+	CollectionMaxRecords int
+	CollectionDeadline   time.Duration
+	// ArchiveDir is where the raw body of every search response is kept;
+	// empty disables the archive. Replay serves responses from the archive
+	// instead of fetching them.
+	ArchiveDir string
+	Replay     bool
+	// BreakerThreshold is how many refusals in a row pause every worker
+	// for BreakerCooldown; 0 never pauses.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// LeaseOwner identifies this worker on the pages it reserves, which
+	// are held for LeaseDuration and renewed while it runs. Pages whose
+	// lease expires are reclaimed by the next reservation.
+	LeaseOwner    string
+	LeaseDuration time.Duration
+	// MaxPageAttempts is how many times a page is tried before it is
+	// dead-lettered; 0 retries it forever.
+	MaxPageAttempts int
+	// SchedulingPolicy is priority, round_robin or oldest, see
+	// GetAndReservePageBatch. ReserveBatchSize is how many pages are
+	// reserved at a time; smaller batches follow the policy more closely
+	// as collections are added or reprioritized.
+	SchedulingPolicy string
+	ReserveBatchSize int
+	// ControlPollInterval is how often a worker with nothing to reserve
+	// checks whether paused work has been resumed.
+	ControlPollInterval time.Duration
 }
 
 type TvpNames struct {
@@ -51,19 +132,51 @@ type DbConfig struct {
 func NewConfig() *Config {
 	proxykey := LoadOptionalString("PROXY_KEY")
 	proxyurl := LoadOptionalString("PROXY_URL")
+	proxypassword := LoadOptionalString("PROXY_PASSWORD")
+	noproxy := LoadDefaultString("PROXY_NO_PROXY", LoadDefaultString("NO_PROXY", os.Getenv("no_proxy")))
 	httptimeout := LoadDefaultInt("HTTP_TIMEOUT_SECS", 30)
 	maxidleconns := LoadDefaultInt("HTTP_MAX_IDLE_CONNS", 100)
 	maxconnsperhost := LoadDefaultInt("HTTP_MAX_CONNS_PER_HOST", 10)
 	idleconntimeout := LoadDefaultInt("HTTP_IDLE_CONN_TIMEOUT_SECS", 30)
+	ratelimitrps := LoadDefaultFloat("HTTP_RATE_LIMIT_RPS", 4)
+	ratelimitburst := LoadDefaultInt("HTTP_RATE_LIMIT_BURST", 4)
+	maxidleconnsperhost := LoadDefaultInt("HTTP_MAX_IDLE_CONNS_PER_HOST", 10)
+	tlshandshaketimeout := LoadDefaultInt("HTTP_TLS_HANDSHAKE_TIMEOUT_SECS", 10)
+	disablehttp2 := LoadDefaultBool("HTTP_DISABLE_HTTP2", false)
+	cookiefile := LoadDefaultString("HTTP_COOKIE_FILE", "")
+	sessionwarmup := LoadDefaultBool("HTTP_SESSION_WARMUP", true)
+	maxbodybytes := LoadDefaultInt("HTTP_MAX_BODY_BYTES", 32<<20)
+	headers := LoadHeaders("HTTP_HEADERS")
+	logrequests := LoadDefaultBool("HTTP_LOG_REQUESTS", false)
+	metrics := LoadDefaultBool("HTTP_METRICS", false)
 	useragent := LoadDefaultString("HTTP_USER_AGENT", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:138.0) Gecko/20100101 Firefox/138.0")
 	maxconcurrency := LoadDefaultInt("PROCESSOR_MAX_CONCURRENCY", 8)
 	retryattempts := LoadDefaultInt("PROCESSOR_RETRY_ATTEMPTS", 3)
 	retrydelay := LoadDefaultInt("PROCESSOR_RETRY_DELAY_MS", 5000)
+	retrymaxdelay := LoadDefaultInt("PROCESSOR_RETRY_MAX_DELAY_MS", 60000)
+	retryaftermax := LoadDefaultInt("PROCESSOR_RETRY_AFTER_MAX_SECS", 120)
 	pagedelay := LoadDefaultInt("PROCESSOR_PAGE_DELAY_MS", 1500)
 	maxpages := LoadDefaultInt("PROCESSOR_MAX_PAGES", 500)
 	batchsize := LoadDefaultInt("PROCESSOR_BATCH_SIZE", 20)
 	flushTimeout := LoadDefaultInt("PROCESSOR_FLUSH_TIMEOUT_SECS", 5)
 	channelSize := LoadDefaultInt("PROCESSOR_CHANNEL_SIZE", 1000)
+	maxPartitionDepth := LoadDefaultInt("PROCESSOR_MAX_PARTITION_DEPTH", 4)
+	maxPrefixLength := LoadDefaultInt("PROCESSOR_MAX_PREFIX_LENGTH", 3)
+	driftTolerance := LoadDefaultInt("PROCESSOR_DRIFT_TOLERANCE", 0)
+	incrementalOrderBy := LoadDefaultString("PROCESSOR_INCREMENTAL_ORDER_BY", "")
+	collectionMaxPages := LoadDefaultInt("PROCESSOR_COLLECTION_MAX_PAGES", 0)
+	collectionMaxRecords := LoadDefaultInt("PROCESSOR_COLLECTION_MAX_RECORDS", 0)
+	collectionDeadline := LoadDefaultInt("PROCESSOR_COLLECTION_DEADLINE_MINS", 0)
+	archiveDir := LoadDefaultString("ARCHIVE_DIR", "")
+	replay := LoadDefaultBool("REPLAY", false)
+	breakerThreshold := LoadDefaultInt("PROCESSOR_BREAKER_THRESHOLD", 5)
+	breakerCooldown := LoadDefaultInt("PROCESSOR_BREAKER_COOLDOWN_SECS", 300)
+	leaseOwner := LoadDefaultString("PROCESSOR_LEASE_OWNER", defaultLeaseOwner())
+	leaseDuration := LoadDefaultInt("PROCESSOR_LEASE_SECS", 300)
+	maxPageAttempts := LoadDefaultInt("PROCESSOR_MAX_PAGE_ATTEMPTS", 5)
+	schedulingPolicy := LoadDefaultString("PROCESSOR_SCHEDULING_POLICY", "priority")
+	reserveBatchSize := LoadDefaultInt("PROCESSOR_RESERVE_BATCH_SIZE", 20)
+	controlPollInterval := LoadDefaultInt("PROCESSOR_CONTROL_POLL_SECS", 30)
 	memTvpName := LoadDefaultString("MEMORIAL_TVP_NAME", "dbo.MemorialTableType")
 	pageTvpName := LoadDefaultString("PAGE_TVP_NAME", "dbo.PageTableType")
 	memIdTvpName := LoadDefaultString("MEMORIAL_ID_TVP_NAME", "dbo.MemorialIdList")
@@ -76,6 +189,22 @@ func NewConfig() *Config {
 			IdleConnTimeout: time.Duration(idleconntimeout) * time.Second,
 			ProxyKey:        proxykey,
 			ProxyUrl:        proxyurl,
+			RateLimitRPS:    ratelimitrps,
+			RateLimitBurst:  ratelimitburst,
+
+			MaxIdleConnsPerHost: maxidleconnsperhost,
+			TLSHandshakeTimeout: time.Duration(tlshandshaketimeout) * time.Second,
+			DisableHTTP2:        disablehttp2,
+
+			ProxyPassword: proxypassword,
+			NoProxy:       noproxy,
+			CookieFile:    cookiefile,
+			SessionWarmup: sessionwarmup,
+
+			MaxBodyBytes: int64(maxbodybytes),
+			Headers:      headers,
+			LogRequests:  logrequests,
+			Metrics:      metrics,
 		},
 		ProcessorConfig: ProcessorConfig{
 			MaxConcurrency: maxconcurrency,
@@ -87,6 +216,35 @@ func NewConfig() *Config {
 			BatchSize:      batchsize,
 			FlushTimeout:   time.Duration(flushTimeout) * time.Second,
 			ChannelSize:    channelSize,
+
+			RetryMaxDelay: time.Duration(retrymaxdelay) * time.Millisecond,
+			RetryAfterMax: time.Duration(retryaftermax) * time.Second,
+
+			MaxPartitionDepth: maxPartitionDepth,
+			MaxPrefixLength:   maxPrefixLength,
+			DriftTolerance:    driftTolerance,
+
+			IncrementalOrderBy: incrementalOrderBy,
+
+			CollectionMaxPages:   collectionMaxPages,
+			CollectionMaxRecords: collectionMaxRecords,
+			CollectionDeadline:   time.Duration(collectionDeadline) * time.Minute,
+
+			ArchiveDir: archiveDir,
+			Replay:     replay,
+
+			BreakerThreshold: breakerThreshold,
+			BreakerCooldown:  time.Duration(breakerCooldown) * time.Second,
+
+			LeaseOwner:    leaseOwner,
+			LeaseDuration: time.Duration(leaseDuration) * time.Second,
+
+			MaxPageAttempts: maxPageAttempts,
+
+			SchedulingPolicy: schedulingPolicy,
+			ReserveBatchSize: reserveBatchSize,
+
+			ControlPollInterval: time.Duration(controlPollInterval) * time.Second,
 		},
 		Tvp: TvpNames{
 			MemorialTvpName:   memTvpName,
@@ -96,6 +254,16 @@ func NewConfig() *Config {
 	}
 }
 
+// defaultLeaseOwner names this process by host and pid, which is unique
+// among workers running at the same time.
+func defaultLeaseOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
 func NewDbConfig() *DbConfig {
 	port, err := strconv.Atoi(os.Getenv("DB_PORT"))
 	if err != nil {
@@ -130,6 +298,22 @@ func LoadDefaultInt(name string, defaultValue int) int {
 	return value
 }
 
+func LoadDefaultFloat(name string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func LoadDefaultBool(name string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func LoadDefaultString(name string, defaultValue string) string {
 	value := os.Getenv(name)
 	if value == "" {
@@ -146,6 +330,22 @@ func LoadRequiredString(name string) string {
 	return value
 }
 
+// LoadHeaders reads HTTP headers given as "Name: value" pairs separated by
+// "|", e.g. "Accept-Language: en-US,en;q=0.5|DNT: 1". Malformed pairs are
+// ignored.
+func LoadHeaders(name string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(name), "|") {
+		key, value, ok := strings.Cut(pair, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		headers[key] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
 func LoadOptionalString(name string) *string {
 	value := os.Getenv(name)
 	if value == "" {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -112,15 +113,138 @@ func (d *DbWriter) InsertPage(ctx context.Context, pages []PageDto) error {
 
 func (d *DbWriter) StartCollection(ctx context.Context, input CollectionParamsDto) (int, error) {
 	var result CollectionStartDto
-	query := `EXEC dbo.sp_StartNewCollection @BatchSize = @p1, @SourceUrl = @p2, @StartedAt = @p3;`
+	query := `EXEC dbo.sp_StartNewCollection @BatchSize = @p1, @SourceUrl = @p2, @StartedAt = @p3, @PlanId = @p4, @TotalPages = @p5, @ExpectedRecords = @p6, @QueryKey = @p7, @Scope = @p8, @Mode = @p9, @Since = @p10,
+		@MaxPages = @p11, @MaxRecords = @p12, @Deadline = @p13, @TruncatedReason = @p14, @Priority = @p15;`
 
-	err := d.db.Get(&result, query, input.BatchSize, input.SourceUrl, sql.NullTime{Time: time.Now(), Valid: true})
+	err := d.db.Get(&result, query, input.BatchSize, input.SourceUrl, sql.NullTime{Time: time.Now(), Valid: true}, input.PlanId, input.TotalPages, input.ExpectedRecords, input.QueryKey, input.Scope, input.Mode, input.Since,
+		input.MaxPages, input.MaxRecords, input.Deadline, input.TruncatedReason, input.Priority)
 	if err != nil {
 		return 0, fmt.Errorf("failed to start collection: %w", err)
 	}
 	return result.CollectionId, nil
 }
 
+func (d *DbWriter) RegisterCrawlPlan(ctx context.Context, name string, definition string) (int, error) {
+	var result PlanStartDto
+	err := d.db.GetContext(ctx, &result, `EXEC dbo.RegisterCrawlPlan @Name = @p1, @Definition = @p2;`, name, definition)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register crawl plan: %w", err)
+	}
+	return result.PlanId, nil
+}
+
+func (d *DbWriter) GetPlanCollections(ctx context.Context, planId int) ([]PlanCollectionDto, error) {
+	var collections []PlanCollectionDto
+	query := `SELECT CollectionId, SourceUrl, Scope, ISNULL(TotalPages, 0) AS TotalPages, ISNULL(IsComplete, 0) AS IsComplete, StartedAt
+		FROM dbo.Collections WHERE PlanId = @PlanId ORDER BY CollectionId;`
+	err := d.db.SelectContext(ctx, &collections, query, sql.Named("PlanId", planId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan collections: %w", err)
+	}
+	return collections, nil
+}
+
+// IsQueryComplete reports whether the search has already been fully
+// collected, including every partition it was split into.
+func (d *DbWriter) IsQueryComplete(ctx context.Context, queryKey string) (bool, error) {
+	var complete bool
+	err := d.db.GetContext(ctx, &complete, `EXEC dbo.IsQueryComplete @QueryKey = @p1;`, queryKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to check query completion: %w", err)
+	}
+	return complete, nil
+}
+
+func (d *DbWriter) InsertQueryPartition(ctx context.Context, input QueryPartitionDto) (int, error) {
+	var result PartitionStartDto
+	query := `EXEC dbo.InsertQueryPartition @ParentPartitionId = @p1, @SourceUrl = @p2, @QueryKey = @p3, @TotalRecords = @p4, @Depth = @p5, @SplitOn = @p6, @CollectionId = @p7, @IsTruncated = @p8;`
+
+	err := d.db.GetContext(ctx, &result, query, input.ParentPartitionId, input.SourceUrl, input.QueryKey, input.TotalRecords, input.Depth, input.SplitOn, input.CollectionId, input.IsTruncated)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert query partition: %w", err)
+	}
+	return result.PartitionId, nil
+}
+
+// CheckPartitionCoverage compares a split partition's total with its
+// children's, flagging the partition truncated when they fall short.
+func (d *DbWriter) CheckPartitionCoverage(ctx context.Context, partitionId int) (PartitionCoverageDto, error) {
+	var coverage PartitionCoverageDto
+	err := d.db.GetContext(ctx, &coverage, "EXEC dbo.CheckPartitionCoverage @PartitionId = @PartitionId",
+		sql.Named("PartitionId", partitionId))
+	if err != nil {
+		return coverage, fmt.Errorf("failed to check partition coverage: %w", err)
+	}
+	return coverage, nil
+}
+
+// FindQueryPartition returns the most recent partition recorded for the
+// search under parentId, or nil if it has not been seen. A nil parentId looks
+// for a root search.
+func (d *DbWriter) FindQueryPartition(ctx context.Context, parentId *int, queryKey string) (*QueryPartitionDto, error) {
+	var partition QueryPartitionDto
+	query := `SELECT TOP(1) PartitionId, ParentPartitionId, SourceUrl, QueryKey, TotalRecords, Depth, SplitOn, CollectionId, IsTruncated
+		FROM dbo.QueryPartitions
+		WHERE QueryKeyHash = CAST(HASHBYTES('SHA2_256', @QueryKey) AS VARBINARY(32))
+		  AND QueryKey = @QueryKey
+		  AND ((@ParentId IS NULL AND ParentPartitionId IS NULL) OR ParentPartitionId = @ParentId)
+		ORDER BY PartitionId DESC;`
+	var parent sql.NullInt32
+	if parentId != nil {
+		parent = sql.NullInt32{Int32: int32(*parentId), Valid: true}
+	}
+	err := d.db.GetContext(ctx, &partition, query, sql.Named("QueryKey", queryKey), sql.Named("ParentId", parent))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find query partition: %w", err)
+	}
+	return &partition, nil
+}
+
+// FindCollection returns the status of the most recent collection seeded for
+// the search, or nil if there is none.
+func (d *DbWriter) FindCollection(ctx context.Context, queryKey string) (*CollectionStatusDto, error) {
+	var collectionId int
+	query := `SELECT TOP(1) CollectionId FROM dbo.Collections
+		WHERE QueryKeyHash = CAST(HASHBYTES('SHA2_256', @QueryKey) AS VARBINARY(32))
+		  AND QueryKey = @QueryKey
+		ORDER BY CollectionId DESC;`
+	err := d.db.GetContext(ctx, &collectionId, query, sql.Named("QueryKey", queryKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find collection: %w", err)
+	}
+	return d.GetCollectionStatus(ctx, collectionId)
+}
+
+// GetQueryWatermark returns the newest change time collected by the last
+// clean incremental run of the search. It is not valid if there has been none.
+func (d *DbWriter) GetQueryWatermark(ctx context.Context, queryKey string) (sql.NullTime, error) {
+	var watermark sql.NullTime
+	err := d.db.GetContext(ctx, &watermark, "EXEC dbo.GetQueryWatermark @QueryKey = @QueryKey", sql.Named("QueryKey", queryKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullTime{}, nil
+	}
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("failed to get query watermark: %w", err)
+	}
+	return watermark, nil
+}
+
+func (d *DbWriter) GetSeededPageNumbers(ctx context.Context, collectionId int) ([]int, error) {
+	var numbers []int
+	err := d.db.SelectContext(ctx, &numbers, "SELECT PageNumber FROM dbo.Pages WHERE CollectionId = @CollectionId",
+		sql.Named("CollectionId", collectionId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seeded page numbers: %w", err)
+	}
+	return numbers, nil
+}
+
 func GetPageBatch(ctx context.Context, tx *sqlx.Tx) ([]Page, error) {
 	pages := make([]Page, 100)
 	query := `SELECT TOP(100) * FROM dbo.Pages WHERE CollectionId in (SELECT CollectionId FROM dbo.Collections WHERE IsComplete = 0) AND IsComplete = 0 ORDER BY PageNumber;`
@@ -131,14 +255,72 @@ func GetPageBatch(ctx context.Context, tx *sqlx.Tx) ([]Page, error) {
 	return pages, nil
 }
 
-func MarkPageCollected(ctx context.Context, pageid int, tx *sqlx.Tx) error {
-	_, err := tx.ExecContext(ctx, "EXEC dbo.MarkPageCollected @PageId = @PageId", sql.Named("PageId", pageid))
+func MarkPageCollected(ctx context.Context, page PageCollectedDto, tx *sqlx.Tx) error {
+	args := append([]any{
+		sql.Named("PageId", page.PageId),
+		sql.Named("RecordCount", page.RecordCount),
+		sql.Named("NewestRecord", page.NewestRecord),
+		sql.Named("Exhausted", page.Exhausted),
+		sql.Named("ArchiveKey", page.ArchiveKey),
+	}, page.Attempt.args()...)
+	_, err := tx.ExecContext(ctx, "EXEC dbo.MarkPageCollected @PageId = @PageId, @RecordCount = @RecordCount, @NewestRecord = @NewestRecord, @Exhausted = @Exhausted, @ArchiveKey = @ArchiveKey, "+attemptParams, args...)
 	if err != nil {
 		return fmt.Errorf("failed to mark page collected: %w", err)
 	}
 	return nil
 }
 
+// attemptParams are the procedure parameters AttemptDto.args fills in.
+const attemptParams = "@StartedAt = @StartedAt, @StatusCode = @StatusCode, @LatencyMs = @LatencyMs"
+
+func (a AttemptDto) args() []any {
+	return []any{
+		sql.Named("StartedAt", sql.NullTime{Time: a.StartedAt, Valid: !a.StartedAt.IsZero()}),
+		sql.Named("StatusCode", sql.NullInt32{Int32: int32(a.StatusCode), Valid: a.StatusCode != 0}),
+		sql.Named("LatencyMs", sql.NullInt64{Int64: a.Latency.Milliseconds(), Valid: a.Latency > 0}),
+	}
+}
+
+func (d *DbWriter) GetCollectionStatus(ctx context.Context, collectionId int) (*CollectionStatusDto, error) {
+	var status CollectionStatusDto
+	query := `SELECT CollectionId, SourceUrl, ISNULL(IsComplete, 0) AS IsComplete, CompletedAt, ISNULL(TotalPages, 0) AS TotalPages,
+			ExpectedRecords, CollectedRecords, ObservedRecords, DriftRecords, CoverageSuspect, TruncatedReason, SeededPages, ISNULL(CollectedPages, 0) AS CollectedPages,
+			ISNULL(DeadPages, 0) AS DeadPages, RecordsSoFar
+		FROM dbo.CollectionProgress WHERE CollectionId = @CollectionId;`
+	err := d.db.GetContext(ctx, &status, query, sql.Named("CollectionId", collectionId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection status: %w", err)
+	}
+	return &status, nil
+}
+
+// RecordCollectionDrift stores the search total seen while fetching one of the
+// collection's pages. If the result set has grown, TotalPages is extended and
+// the caller is expected to append the pages after PreviousPages. Nothing is
+// recorded for a collection that is finished, paused or cancelled.
+func (d *DbWriter) RecordCollectionDrift(ctx context.Context, collectionId int, observedRecords int, tolerance int) (*CollectionDriftDto, error) {
+	var drift CollectionDriftDto
+	err := d.db.GetContext(ctx, &drift, "EXEC dbo.RecordCollectionDrift @CollectionId = @CollectionId, @ObservedRecords = @ObservedRecords, @Tolerance = @Tolerance",
+		sql.Named("CollectionId", collectionId),
+		sql.Named("ObservedRecords", observedRecords),
+		sql.Named("Tolerance", tolerance))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record collection drift: %w", err)
+	}
+	return &drift, nil
+}
+
+func (d *DbWriter) SetPartitionCollection(ctx context.Context, partitionId int, collectionId int, totalRecords int) error {
+	_, err := d.db.ExecContext(ctx, "EXEC dbo.SetPartitionCollection @PartitionId = @PartitionId, @CollectionId = @CollectionId, @TotalRecords = @TotalRecords",
+		sql.Named("PartitionId", partitionId),
+		sql.Named("CollectionId", collectionId),
+		sql.Named("TotalRecords", totalRecords))
+	if err != nil {
+		return fmt.Errorf("failed to set partition collection: %w", err)
+	}
+	return nil
+}
+
 func (d *DbWriter) FreshTransaction(ctx context.Context) (*sqlx.Tx, error) {
 	tx, err := d.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -147,36 +329,166 @@ func (d *DbWriter) FreshTransaction(ctx context.Context) (*sqlx.Tx, error) {
 	return tx, nil
 }
 
-func (d *DbWriter) GetReservedPageBatch(ctx context.Context) ([]Page, error) {
+// GetReservedPageBatch reserves up to batchSize pages for owner, which holds
+// them until lease runs out unless it renews or releases them. Pages are
+// returned in the order the configured scheduling policy wants them fetched.
+func (d *DbWriter) GetReservedPageBatch(ctx context.Context, owner string, lease time.Duration, batchSize int) ([]Page, error) {
 	var pages []Page
 	err := d.db.SelectContext(ctx, &pages,
-		"EXEC dbo.GetAndReservePageBatch @BatchSize = @BatchSize",
-		sql.Named("BatchSize", 100))
+		"EXEC dbo.GetAndReservePageBatch @BatchSize = @BatchSize, @Owner = @Owner, @LeaseSeconds = @LeaseSeconds, @MaxAttempts = @MaxAttempts, @Policy = @Policy",
+		sql.Named("BatchSize", batchSize),
+		sql.Named("Owner", owner),
+		sql.Named("LeaseSeconds", int(lease.Seconds())),
+		sql.Named("MaxAttempts", d.cfg.ProcessorConfig.MaxPageAttempts),
+		sql.Named("Policy", d.cfg.ProcessorConfig.SchedulingPolicy))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get page batch: %w", err)
 	}
 	return pages, nil
 }
 
-func (d *DbWriter) SetPageFailed(ctx context.Context, pageid int) error {
+// RenewPageLeases extends every lease owner holds by lease and returns how
+// many pages it still holds.
+func (d *DbWriter) RenewPageLeases(ctx context.Context, owner string, lease time.Duration) (int, error) {
+	var renewed int
+	err := d.db.GetContext(ctx, &renewed, "EXEC dbo.RenewPageLeases @Owner = @Owner, @LeaseSeconds = @LeaseSeconds",
+		sql.Named("Owner", owner),
+		sql.Named("LeaseSeconds", int(lease.Seconds())))
+	if err != nil {
+		return 0, fmt.Errorf("failed to renew page leases: %w", err)
+	}
+	return renewed, nil
+}
+
+// ReleasePageLeases returns the pages owner has not finished to the queue.
+func (d *DbWriter) ReleasePageLeases(ctx context.Context, owner string) (int, error) {
+	var released int
+	err := d.db.GetContext(ctx, &released, "EXEC dbo.ReleasePageLeases @Owner = @Owner",
+		sql.Named("Owner", owner))
+	if err != nil {
+		return 0, fmt.Errorf("failed to release page leases: %w", err)
+	}
+	return released, nil
+}
+
+// ReleasePageLease returns a single page owner holds to the queue.
+func (d *DbWriter) ReleasePageLease(ctx context.Context, owner string, pageId int) error {
+	var released int
+	err := d.db.GetContext(ctx, &released, "EXEC dbo.ReleasePageLeases @Owner = @Owner, @PageId = @PageId",
+		sql.Named("Owner", owner),
+		sql.Named("PageId", pageId))
+	if err != nil {
+		return fmt.Errorf("failed to release page lease: %w", err)
+	}
+	return nil
+}
+
+// IsCollectionActive reports whether the collection's pages may still be
+// fetched, i.e. neither it nor the whole crawl is paused or cancelled.
+func (d *DbWriter) IsCollectionActive(ctx context.Context, collectionId int) (bool, error) {
+	var active bool
+	query := `SELECT CAST(CASE WHEN c.State = N'active' AND NOT EXISTS (SELECT 1 FROM dbo.CrawlControl WHERE Paused = 1)
+		THEN 1 ELSE 0 END AS BIT)
+		FROM dbo.Collections c WHERE c.CollectionId = @CollectionId`
+	if err := d.db.GetContext(ctx, &active, query, sql.Named("CollectionId", collectionId)); err != nil {
+		return false, fmt.Errorf("failed to get collection state: %w", err)
+	}
+	return active, nil
+}
+
+// SetPageFailed records why the page's last attempt failed and adds it to
+// the page's failure history. Pages whose failure is permanent, or that have
+// used up their attempts, are dead-lettered rather than retried.
+func (d *DbWriter) SetPageFailed(ctx context.Context, page PageFailedDto) error {
 	tx, err := d.FreshTransaction(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer tx.Rollback()
-	_, err = tx.ExecContext(ctx, "EXEC dbo.MarkPageFailed @PageId = @PageId", sql.Named("PageId", pageid))
+	args := append([]any{
+		sql.Named("PageId", page.PageId),
+		sql.Named("Reason", page.Reason),
+		sql.Named("FailureClass", page.FailureClass),
+		sql.Named("ArchiveKey", page.ArchiveKey),
+		sql.Named("FailureCause", sql.NullString{String: page.FailureCause, Valid: page.FailureCause != ""}),
+		sql.Named("MaxAttempts", d.cfg.ProcessorConfig.MaxPageAttempts),
+	}, page.Attempt.args()...)
+	_, err = tx.ExecContext(ctx, "EXEC dbo.MarkPageFailed @PageId = @PageId, @Reason = @Reason, @FailureClass = @FailureClass, @ArchiveKey = @ArchiveKey, @FailureCause = @FailureCause, @MaxAttempts = @MaxAttempts, "+attemptParams, args...)
 	if err != nil {
 		return fmt.Errorf("failed to set page failed: %w", err)
 	}
 	return tx.Commit()
 }
 
-func (d *DbWriter) SetPageFailedNoTx(ctx context.Context, pageid int) error {
-	_, err := d.db.ExecContext(ctx, "EXEC dbo.MarkPageFailed @PageId = @PageId", sql.Named("PageId", pageid))
+// ControlCrawl pauses, resumes or cancels a collection, or the whole crawl
+// when collectionId is 0, and returns how many collections were affected.
+// Workers notice the change on their next reservation, and skip pages they
+// had already queued before fetching them.
+func (d *DbWriter) ControlCrawl(ctx context.Context, action string, collectionId int, reason string) (int, error) {
+	var affected int
+	err := d.db.GetContext(ctx, &affected, "EXEC dbo.ControlCrawl @Action = @Action, @CollectionId = @CollectionId, @Reason = @Reason",
+		sql.Named("Action", action),
+		sql.Named("CollectionId", sql.NullInt32{Int32: int32(collectionId), Valid: collectionId != 0}),
+		sql.Named("Reason", sql.NullString{String: reason, Valid: reason != ""}))
 	if err != nil {
-		return fmt.Errorf("failed to set page failed: %w", err)
+		return 0, fmt.Errorf("failed to %s crawl: %w", action, err)
 	}
-	return nil
+	return affected, nil
+}
+
+// SetCollectionPriority changes the priority of a collection, or of every
+// unfinished collection a plan seeded when planId is set, and returns how
+// many collections were changed.
+func (d *DbWriter) SetCollectionPriority(ctx context.Context, priority int, collectionId int, planId int) (int, error) {
+	var updated int
+	err := d.db.GetContext(ctx, &updated, "EXEC dbo.SetCollectionPriority @Priority = @Priority, @CollectionId = @CollectionId, @PlanId = @PlanId",
+		sql.Named("Priority", priority),
+		sql.Named("CollectionId", sql.NullInt32{Int32: int32(collectionId), Valid: collectionId != 0}),
+		sql.Named("PlanId", sql.NullInt32{Int32: int32(planId), Valid: planId != 0}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to set collection priority: %w", err)
+	}
+	return updated, nil
+}
+
+// GetPausedWork reports whether the crawl is paused and how many open pages
+// are waiting on it or on paused collections.
+func (d *DbWriter) GetPausedWork(ctx context.Context) (PausedWorkDto, error) {
+	var paused PausedWorkDto
+	if err := d.db.GetContext(ctx, &paused, "EXEC dbo.GetPausedWork"); err != nil {
+		return paused, fmt.Errorf("failed to get paused work: %w", err)
+	}
+	return paused, nil
+}
+
+// GetDeadPageReasons breaks a collection's dead pages down by why they died.
+// A collectionId of 0 covers every collection.
+func (d *DbWriter) GetDeadPageReasons(ctx context.Context, collectionId int) ([]DeadPageReasonDto, error) {
+	var reasons []DeadPageReasonDto
+	err := d.db.SelectContext(ctx, &reasons,
+		`SELECT CollectionId, DeadReason, FailureCause, Pages, ISNULL(SampleError, '') AS SampleError, LastFailedAt
+		FROM dbo.DeadPageReasons
+		WHERE @CollectionId = 0 OR CollectionId = @CollectionId
+		ORDER BY CollectionId, Pages DESC`,
+		sql.Named("CollectionId", collectionId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead page reasons: %w", err)
+	}
+	return reasons, nil
+}
+
+// RequeueDeadPages returns dead pages to the queue with fresh attempts and
+// reports how many were requeued. A collectionId of 0 covers every
+// collection, and an empty cause every failure cause.
+func (d *DbWriter) RequeueDeadPages(ctx context.Context, collectionId int, cause string) (int, error) {
+	var requeued int
+	err := d.db.GetContext(ctx, &requeued, "EXEC dbo.RequeueDeadPages @CollectionId = @CollectionId, @FailureCause = @FailureCause",
+		sql.Named("CollectionId", sql.NullInt32{Int32: int32(collectionId), Valid: collectionId != 0}),
+		sql.Named("FailureCause", sql.NullString{String: cause, Valid: cause != ""}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue dead pages: %w", err)
+	}
+	return requeued, nil
 }
 
 func GetAllSeenMemorialIds(ctx context.Context, checkIds []int64, tx *sqlx.Tx, memtvpname string) ([]int64, error) {
@@ -27,15 +27,180 @@ type PageDto struct {
 }
 
 type CollectionParamsDto struct {
-	BatchSize int          `db:"BatchSize"`
-	SourceUrl string       `db:"SourceUrl"`
-	StartedAt sql.NullTime `db:"StartedAt"`
+	BatchSize       int            `db:"BatchSize"`
+	SourceUrl       string         `db:"SourceUrl"`
+	StartedAt       sql.NullTime   `db:"StartedAt"`
+	PlanId          sql.NullInt32  `db:"PlanId"`
+	TotalPages      int            `db:"TotalPages"`
+	ExpectedRecords int            `db:"ExpectedRecords"`
+	QueryKey        string         `db:"QueryKey"`
+	Scope           sql.NullString `db:"Scope"`
+	Mode            string         `db:"Mode"`
+	Since           sql.NullTime   `db:"Since"`
+	MaxPages        sql.NullInt32  `db:"MaxPages"`
+	MaxRecords      sql.NullInt32  `db:"MaxRecords"`
+	Deadline        sql.NullTime   `db:"Deadline"`
+	TruncatedReason sql.NullString `db:"TruncatedReason"`
+	// Priority orders the collection's pages against other collections',
+	// higher first; see the scheduling policies.
+	Priority int `db:"Priority"`
+}
+
+// Reasons a collection stopped short of every page of its search.
+const (
+	TruncatedPageCap    = "page_cap"
+	TruncatedMaxPages   = "max_pages"
+	TruncatedMaxRecords = "max_records"
+	TruncatedDeadline   = "deadline"
+)
+
+const (
+	CollectionModeFull        = "full"
+	CollectionModeIncremental = "incremental"
+)
+
+// Scheduling policies decide which collections' pages are reserved first.
+// Pages within a collection are always reserved in page order.
+const (
+	// SchedulePriority works through collections by descending Priority,
+	// oldest first among equals, so urgent collections are never starved.
+	SchedulePriority = "priority"
+	// ScheduleRoundRobin interleaves collections, giving one of Priority n
+	// n + 1 pages for every page of a Priority 0 collection.
+	ScheduleRoundRobin = "round_robin"
+	// ScheduleOldest finishes the oldest collections first.
+	ScheduleOldest = "oldest"
+)
+
+// Actions ControlCrawl takes on a collection or the whole crawl.
+const (
+	ControlPause  = "pause"
+	ControlResume = "resume"
+	ControlCancel = "cancel"
+)
+
+// PausedWorkDto is how much open work is held back by a pause.
+type PausedWorkDto struct {
+	CrawlPaused bool `db:"CrawlPaused"`
+	PausedPages int  `db:"PausedPages"`
+}
+
+// PageCollectedDto is what a successfully fetched page reports back.
+type PageCollectedDto struct {
+	PageId      int
+	RecordCount int
+	// NewestRecord is the most recent change time among the page's records.
+	NewestRecord sql.NullTime
+	// Exhausted marks an incremental page with nothing newer than the
+	// watermark, so later pages can be skipped.
+	Exhausted bool
+	// ArchiveKey locates the page's raw response in the archive, if kept.
+	ArchiveKey sql.NullString
+	Attempt    AttemptDto
+}
+
+// AttemptDto is when a page attempt ran and how its last response went,
+// recorded in its PageAttempts row.
+type AttemptDto struct {
+	StartedAt time.Time
+	// StatusCode and Latency are zero if no response was received.
+	StatusCode int
+	Latency    time.Duration
+}
+
+// PageFailedDto is what a failed page attempt reports back.
+type PageFailedDto struct {
+	PageId int
+	// FailureClass is retryable or permanent, and FailureCause a short name
+	// for what went wrong, such as http_500 or html, for grouping.
+	FailureClass string
+	FailureCause string
+	Reason       string
+	// ArchiveKey locates the response that failed, if one was archived.
+	ArchiveKey sql.NullString
+	Attempt    AttemptDto
+}
+
+// DeadPageReasonDto counts a collection's dead pages that died for the same
+// reason and cause.
+type DeadPageReasonDto struct {
+	CollectionId int       `db:"CollectionId"`
+	DeadReason   string    `db:"DeadReason"`
+	FailureCause string    `db:"FailureCause"`
+	Pages        int       `db:"Pages"`
+	SampleError  string    `db:"SampleError"`
+	LastFailedAt time.Time `db:"LastFailedAt"`
+}
+
+type CollectionStatusDto struct {
+	CollectionId     int            `db:"CollectionId"`
+	SourceUrl        string         `db:"SourceUrl"`
+	IsComplete       bool           `db:"IsComplete"`
+	CompletedAt      sql.NullTime   `db:"CompletedAt"`
+	TotalPages       int            `db:"TotalPages"`
+	ExpectedRecords  sql.NullInt32  `db:"ExpectedRecords"`
+	CollectedRecords sql.NullInt32  `db:"CollectedRecords"`
+	ObservedRecords  sql.NullInt32  `db:"ObservedRecords"`
+	DriftRecords     int            `db:"DriftRecords"`
+	CoverageSuspect  bool           `db:"CoverageSuspect"`
+	TruncatedReason  sql.NullString `db:"TruncatedReason"`
+	SeededPages      int            `db:"SeededPages"`
+	CollectedPages   int            `db:"CollectedPages"`
+	DeadPages        int            `db:"DeadPages"`
+	RecordsSoFar     int            `db:"RecordsSoFar"`
+}
+
+// CollectionDriftDto is the outcome of recording a search total observed
+// while a collection was being fetched.
+type CollectionDriftDto struct {
+	// Updated is false when the collection is no longer active, in which
+	// case nothing was recorded.
+	Updated         bool `db:"Updated"`
+	PreviousPages   int  `db:"PreviousPages"`
+	TotalPages      int  `db:"TotalPages"`
+	CoverageSuspect bool `db:"CoverageSuspect"`
 }
 
 type CollectionStartDto struct {
 	CollectionId int `db:"NewRecordID"`
 }
 
+type QueryPartitionDto struct {
+	PartitionId       int            `db:"PartitionId"`
+	ParentPartitionId sql.NullInt32  `db:"ParentPartitionId"`
+	SourceUrl         string         `db:"SourceUrl"`
+	QueryKey          string         `db:"QueryKey"`
+	TotalRecords      int            `db:"TotalRecords"`
+	Depth             int            `db:"Depth"`
+	SplitOn           sql.NullString `db:"SplitOn"`
+	CollectionId      sql.NullInt32  `db:"CollectionId"`
+	IsTruncated       bool           `db:"IsTruncated"`
+}
+
+// PartitionCoverageDto compares a split partition's total with the records
+// its children report.
+type PartitionCoverageDto struct {
+	TotalRecords int `db:"TotalRecords"`
+	ChildRecords int `db:"ChildRecords"`
+}
+
+type PlanStartDto struct {
+	PlanId int `db:"NewRecordID"`
+}
+
+type PlanCollectionDto struct {
+	CollectionId int            `db:"CollectionId"`
+	SourceUrl    string         `db:"SourceUrl"`
+	Scope        sql.NullString `db:"Scope"`
+	TotalPages   int            `db:"TotalPages"`
+	IsComplete   bool           `db:"IsComplete"`
+	StartedAt    sql.NullTime   `db:"StartedAt"`
+}
+
+type PartitionStartDto struct {
+	PartitionId int `db:"NewRecordID"`
+}
+
 type DuplicateEntry struct {
 	MemorialId   int64  `json:"MemorialId" db:"memorial_id"`
 	CollectionId int    `json:"CollectionId" db:"collection_id"`
@@ -54,6 +219,13 @@ type Page struct {
 	LastAttemptAt *time.Time `db:"LastAttemptAt"`
 	CreatedAt     time.Time  `db:"CreatedAt"`
 	UpdatedAt     time.Time  `db:"UpdatedAt"`
+	// ExpectedRecords is the collection's search total when the page was
+	// reserved.
+	ExpectedRecords sql.NullInt32 `db:"ExpectedRecords"`
+	// Mode and Since are the collection's crawl mode and the watermark an
+	// incremental collection started from.
+	Mode  string       `db:"Mode"`
+	Since sql.NullTime `db:"Since"`
 }
 
 func NewMemorialDto(url string, memorial search.Memorial, collectionId int, pagenumber int) (*MemorialDto, error) {
@@ -82,10 +254,29 @@ func ConvertPageMemorials(memorials []search.Memorial, url string, collectionId
 	return dtos, nil
 }
 
-func GetNewCollectionParams(batchSize int, sourceUrl string) CollectionParamsDto {
+func GetNewCollectionParams(batchSize int, sourceUrl string, queryKey string, scope string, planId int, totalRecords int) CollectionParamsDto {
 	return CollectionParamsDto{
-		BatchSize: batchSize,
-		SourceUrl: sourceUrl,
-		StartedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		BatchSize:       batchSize,
+		SourceUrl:       sourceUrl,
+		QueryKey:        queryKey,
+		Scope:           sql.NullString{String: scope, Valid: scope != ""},
+		Mode:            CollectionModeFull,
+		StartedAt:       sql.NullTime{Time: time.Now(), Valid: true},
+		PlanId:          sql.NullInt32{Int32: int32(planId), Valid: planId > 0},
+		TotalPages:      (totalRecords + batchSize - 1) / batchSize,
+		ExpectedRecords: totalRecords,
+	}
+}
+
+func NewPartitionParams(parentId *int, sourceUrl string, queryKey string, totalRecords int, depth int) QueryPartitionDto {
+	dto := QueryPartitionDto{
+		SourceUrl:    sourceUrl,
+		QueryKey:     queryKey,
+		TotalRecords: totalRecords,
+		Depth:        depth,
+	}
+	if parentId != nil {
+		dto.ParentPartitionId = sql.NullInt32{Int32: int32(*parentId), Valid: true}
 	}
+	return dto
 }
@@ -13,38 +13,63 @@ import (
 )
 
 type Pager struct {
-	proc  *processor.Processor
-	db    *db.DbWriter
-	pproc *processor.PageProcessor
-	cfg   *config.Config
+	proc    *processor.Processor
+	db      *db.DbWriter
+	pproc   *processor.PageProcessor
+	cfg     *config.Config
+	breaker *processor.CircuitBreaker
 }
 
 func NewPager(proc *processor.Processor, db *db.DbWriter, pproc *processor.PageProcessor, cfg *config.Config) *Pager {
 	return &Pager{
-		proc:  proc,
-		db:    db,
-		pproc: pproc,
-		cfg:   cfg,
+		proc:    proc,
+		db:      db,
+		pproc:   pproc,
+		cfg:     cfg,
+		breaker: processor.NewCircuitBreaker(cfg.ProcessorConfig.BreakerThreshold, cfg.ProcessorConfig.BreakerCooldown),
 	}
 }
 
 func (p *Pager) WorkerPool(ctx context.Context) error {
 	fmt.Println("Starting worker pool for page processing...")
-	pagequeue := make(chan db.Page, 1000)
+	// pages queue in the order they were reserved, so only a batch is held
+	// at a time to keep the scheduling policy in charge of what runs next
+	pagequeue := make(chan db.Page, p.cfg.ProcessorConfig.ReserveBatchSize)
 	errchan := make(chan error, 1)
 
+	workctx, stopWork := context.WithCancel(ctx)
+	defer stopWork()
+
 	var wg sync.WaitGroup
 	for range p.proc.MaxConcurrency {
 		wg.Add(1)
-		go p.pageConsumer(ctx, pagequeue, errchan, p.pproc.Channel(), &wg)
+		go p.pageConsumer(workctx, pagequeue, errchan, p.pproc.Channel(), &wg)
 	}
 
-	go p.pageProducer(ctx, pagequeue, errchan)
+	go p.pageProducer(workctx, pagequeue, errchan)
+
+	leasectx, stopLeases := context.WithCancel(ctx)
+	go p.renewLeases(leasectx)
+	defer stopLeases()
 
-	return p.WaitForCompletion(ctx, &wg, errchan)
+	done := p.drain(ctx, &wg)
+	err := p.WaitForCompletion(ctx, done, errchan)
+
+	// consumers may still be fetching pages they hold leases on, so they are
+	// stopped and their updates written before the rest are handed back
+	stopWork()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	stopLeases()
+	p.releaseLeases()
+	return err
 }
 
-func (p *Pager) WaitForCompletion(ctx context.Context, wg *sync.WaitGroup, errchan <-chan error) error {
+// drain returns a channel closed once every consumer has stopped and the
+// updates they sent have been written.
+func (p *Pager) drain(ctx context.Context, wg *sync.WaitGroup) <-chan struct{} {
 	done := make(chan struct{})
 	go func() {
 		wg.Wait()
@@ -52,7 +77,46 @@ func (p *Pager) WaitForCompletion(ctx context.Context, wg *sync.WaitGroup, errch
 		p.pproc.WaitForCompletion(ctx)
 		close(done)
 	}()
+	return done
+}
 
+// renewLeases keeps the pages this worker has reserved from being reclaimed
+// until ctx is done.
+func (p *Pager) renewLeases(ctx context.Context) {
+	lease := p.cfg.ProcessorConfig.LeaseDuration
+	if lease <= 0 {
+		return
+	}
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := p.db.RenewPageLeases(ctx, p.cfg.ProcessorConfig.LeaseOwner, lease); err != nil {
+				fmt.Printf("Error renewing page leases: %v\n", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// releaseLeases returns the pages this worker did not finish to the queue.
+// It runs after ctx may have been cancelled, so it uses its own.
+func (p *Pager) releaseLeases() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	released, err := p.db.ReleasePageLeases(ctx, p.cfg.ProcessorConfig.LeaseOwner)
+	if err != nil {
+		fmt.Printf("Error releasing page leases: %v\n", err)
+		return
+	}
+	if released > 0 {
+		fmt.Printf("Released %d unfinished pages\n", released)
+	}
+}
+
+func (p *Pager) WaitForCompletion(ctx context.Context, done <-chan struct{}, errchan <-chan error) error {
 	select {
 	case err := <-errchan:
 		return err
@@ -65,6 +129,7 @@ func (p *Pager) WaitForCompletion(ctx context.Context, wg *sync.WaitGroup, errch
 
 func (p *Pager) pageProducer(ctx context.Context, pagequeue chan<- db.Page, errchan chan<- error) {
 	defer close(pagequeue)
+	waiting := false
 	for {
 		select {
 		case <-ctx.Done():
@@ -72,7 +137,7 @@ func (p *Pager) pageProducer(ctx context.Context, pagequeue chan<- db.Page, errc
 		default:
 		}
 
-		pagebatch, err := p.db.GetReservedPageBatch(ctx)
+		pagebatch, err := p.db.GetReservedPageBatch(ctx, p.cfg.ProcessorConfig.LeaseOwner, p.cfg.ProcessorConfig.LeaseDuration, p.cfg.ProcessorConfig.ReserveBatchSize)
 		if err != nil {
 			select {
 			case errchan <- err:
@@ -82,8 +147,32 @@ func (p *Pager) pageProducer(ctx context.Context, pagequeue chan<- db.Page, errc
 		}
 
 		if len(pagebatch) == 0 {
-			fmt.Println("No more pages to process, ending producer.")
-			return
+			paused, err := p.db.GetPausedWork(ctx)
+			if err != nil {
+				select {
+				case errchan <- err:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if paused.PausedPages == 0 {
+				fmt.Println("No more pages to process, ending producer.")
+				return
+			}
+			if !waiting {
+				fmt.Printf("%d pages are paused (crawl paused: %v), waiting for them to be resumed\n", paused.PausedPages, paused.CrawlPaused)
+				waiting = true
+			}
+			select {
+			case <-time.After(p.cfg.ProcessorConfig.ControlPollInterval):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		if waiting {
+			fmt.Println("Paused pages resumed")
+			waiting = false
 		}
 		for _, page := range pagebatch {
 			select {
@@ -105,23 +194,49 @@ func (p *Pager) pageConsumer(ctx context.Context, pagequeue <-chan db.Page, errc
 			if !ok {
 				return
 			}
-			if err := jitteredPause(ctx, p.cfg.ProcessorConfig.PageDelay, 0.3); err != nil {
+			if err := p.pace(ctx); err != nil {
 				select {
-				case errchan <- fmt.Errorf("jittered pause error: %w", err):
+				case errchan <- fmt.Errorf("page pause error: %w", err):
 				case <-ctx.Done():
 				}
 				return
 			}
-			err := p.processPage(ctx, page)
+			// pages queued before a pause or cancel are not fetched
+			active, err := p.db.IsCollectionActive(ctx, page.CollectionId)
+			if err != nil {
+				select {
+				case errchan <- err:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if !active {
+				fmt.Printf("Skipping page %d of collection %d, which is paused or cancelled\n", page.PageNumber, page.CollectionId)
+				if err := p.db.ReleasePageLease(ctx, p.cfg.ProcessorConfig.LeaseOwner, page.PageId); err != nil {
+					fmt.Printf("Error releasing page %d: %v\n", page.PageId, err)
+				}
+				continue
+			}
+			started := time.Now()
+			result, err := p.processPage(ctx, page)
+			if err != nil && ctx.Err() != nil {
+				// stopped mid-fetch; the page goes back to the queue with
+				// the leases that are released on the way out
+				return
+			}
+			if !p.cfg.ProcessorConfig.Replay {
+				p.breaker.Record(err)
+			}
 
 			var updatePage processor.PageUpdate
 			if err != nil {
 				fmt.Printf("Error processing page %d: %v\n", page.PageNumber, err)
-				updatePage = processor.GetPageUpdate(&page, 1, err)
+				updatePage = processor.GetPageUpdate(&page, 1, result, err)
 			} else {
 				// fmt.Printf("Successfully processed page %d\n", page.PageNumber)
-				updatePage = processor.GetPageUpdate(&page, 0, nil)
+				updatePage = processor.GetPageUpdate(&page, 0, result, nil)
 			}
+			updatePage.StartedAt = started
 
 			pageup <- updatePage
 		case <-ctx.Done():
@@ -130,8 +245,22 @@ func (p *Pager) pageConsumer(ctx context.Context, pagequeue <-chan db.Page, errc
 	}
 }
 
-func (p *Pager) processPage(ctx context.Context, page db.Page) error {
-	return p.proc.ProcessSingleSearch(ctx, &page)
+// pace waits before the next page is fetched: the page delay, and then for
+// as long as the circuit breaker is open, since every worker holds off while
+// the site is refusing requests. Replayed pages come from the archive, so
+// they are not paced.
+func (p *Pager) pace(ctx context.Context) error {
+	if p.cfg.ProcessorConfig.Replay {
+		return nil
+	}
+	if err := jitteredPause(ctx, p.cfg.ProcessorConfig.PageDelay, 0.3); err != nil {
+		return err
+	}
+	return p.breaker.Wait(ctx)
+}
+
+func (p *Pager) processPage(ctx context.Context, page db.Page) (processor.PageResult, error) {
+	return p.proc.ProcessSingleSearch(ctx, p.db, &page)
 }
 
 func jitteredPause(ctx context.Context, baseDelay time.Duration, jitterPercent float64) error {
@@ -0,0 +1,387 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/search"
+)
+
+const defaultAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Plan is a declarative description of the searches a crawl should seed.
+// Every seed is combined with every value of every generator, so a seed with
+// an alphabet generator on lastName and another on firstName produces the
+// same 676 searches as the old GEN loop.
+type Plan struct {
+	Name       string              `json:"name"`
+	Limits     Limits              `json:"limits"`
+	Seeds      []map[string]string `json:"seeds"`
+	Generators []Generator         `json:"generators"`
+	// Refresh re-seeds searches that have already been collected. By default
+	// they are skipped, so re-running a plan only seeds what is outstanding.
+	Refresh bool `json:"refresh"`
+	// Incremental collects only records changed since each search's last
+	// clean incremental run.
+	Incremental bool `json:"incremental"`
+	// Priority is given to every collection the plan seeds; higher
+	// priority collections are fetched sooner.
+	Priority int `json:"priority,omitempty"`
+}
+
+type Limits struct {
+	// MaxQueries caps how many searches the plan expands to; 0 is unlimited.
+	MaxQueries int `json:"maxQueries"`
+	// PageSize is the limit used for every search; 0 uses the configured
+	// batch size.
+	PageSize int `json:"pageSize"`
+	// MaxPages, MaxRecords and Deadline budget each collection the plan
+	// seeds; unset limits use the configured defaults. Deadline is a
+	// duration such as "6h", measured from when the collection is seeded.
+	MaxPages   int    `json:"maxPages,omitempty"`
+	MaxRecords int    `json:"maxRecords,omitempty"`
+	Deadline   string `json:"deadline,omitempty"`
+}
+
+// DeadlineDuration returns the parsed Deadline, or 0 if it is not set.
+func (l Limits) DeadlineDuration() (time.Duration, error) {
+	if l.Deadline == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(l.Deadline)
+	if err != nil {
+		return 0, fmt.Errorf("invalid deadline %q: %w", l.Deadline, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("deadline must be positive, got %s", l.Deadline)
+	}
+	return d, nil
+}
+
+// Generator produces one search per value for a single search parameter.
+// Params are named as in the search URL, e.g. lastName or deathyear.
+type Generator struct {
+	Type  string `json:"type"`
+	Param string `json:"param"`
+
+	// alphabet
+	Letters string  `json:"letters,omitempty"`
+	Length  int     `json:"length,omitempty"`
+	Suffix  *string `json:"suffix,omitempty"`
+
+	// years: either From/To, or Last for the most recent years up to and
+	// including the current one. Bucket groups years into a single search
+	// centred in the bucket using the year filter, so it must be one of the
+	// widths the filter supports (1, 3, 7, 11, 21 or 51). A last bucket that
+	// would run past To is covered by narrower ones instead. Step, which
+	// defaults to Bucket, may skip years but not overlap them.
+	From   int `json:"from,omitempty"`
+	To     int `json:"to,omitempty"`
+	Last   int `json:"last,omitempty"`
+	Step   int `json:"step,omitempty"`
+	Bucket int `json:"bucket,omitempty"`
+
+	// values
+	Values []string `json:"values,omitempty"`
+}
+
+const (
+	GeneratorAlphabet = "alphabet"
+	GeneratorYears    = "years"
+	GeneratorValues   = "values"
+)
+
+// Load reads and validates a plan file.
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var p Plan
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// bucketFilters maps a year bucket width to the +/- year filter that covers it.
+var bucketFilters = map[int]string{1: "", 3: "1", 7: "3", 11: "5", 21: "10", 51: "25"}
+
+// Default is the plan the GEN environment variable runs: every two-letter
+// last/first name wildcard pair for deaths in the current year.
+func Default() *Plan {
+	return &Plan{
+		Name: "default",
+		Generators: []Generator{
+			{Type: GeneratorYears, Param: "deathyear", Last: 1},
+			{Type: GeneratorAlphabet, Param: "lastName"},
+			{Type: GeneratorAlphabet, Param: "firstName"},
+		},
+	}
+}
+
+// YearSweep seeds one search per year (or per bucket of years) of param,
+// which is deathyear or birthyear. Set from and to for a fixed range, or last
+// for the most recent years. Searches that are too large are partitioned when
+// they are seeded.
+func YearSweep(param string, from, to, last, bucket int) (*Plan, error) {
+	if param != "deathyear" && param != "birthyear" {
+		return nil, fmt.Errorf("year sweeps run over deathyear or birthyear, not %q", param)
+	}
+	p := &Plan{
+		Name: fmt.Sprintf("%s-sweep", param),
+		Generators: []Generator{
+			{Type: GeneratorYears, Param: param, From: from, To: to, Last: last, Bucket: bucket},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Cemeteries seeds a search for every memorial in each of the cemeteries in
+// ids, a comma separated list of cemetery IDs. Cemeteries too large to page
+// through are partitioned by name when they are seeded.
+func Cemeteries(ids string) (*Plan, error) {
+	var values []string
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid cemetery ID %q", id)
+		}
+		values = append(values, id)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no cemetery IDs given")
+	}
+	p := &Plan{
+		Name: "cemeteries",
+		Generators: []Generator{
+			{Type: GeneratorValues, Param: "cemeteryId", Values: values},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Plan) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("plan must have a name")
+	}
+	if p.Limits.MaxQueries < 0 || p.Limits.PageSize < 0 || p.Limits.MaxPages < 0 || p.Limits.MaxRecords < 0 {
+		return fmt.Errorf("plan %s: limits must not be negative", p.Name)
+	}
+	if _, err := p.Limits.DeadlineDuration(); err != nil {
+		return fmt.Errorf("plan %s: %w", p.Name, err)
+	}
+	for i, g := range p.Generators {
+		if _, err := g.values(); err != nil {
+			return fmt.Errorf("plan %s: generator %d: %w", p.Name, i, err)
+		}
+	}
+	return nil
+}
+
+// Definition is the canonical JSON form stored alongside collections.
+func (p *Plan) Definition() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	return string(data), nil
+}
+
+// Expand returns the searches described by the plan, in seed then generator
+// order. defaultLimit is used when the plan does not set a page size.
+func (p *Plan) Expand(defaultLimit int) ([]search.SearchParams, error) {
+	limit := p.Limits.PageSize
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	seeds := p.Seeds
+	if len(seeds) == 0 {
+		seeds = []map[string]string{{}}
+	}
+	var queries []search.SearchParams
+	for _, seed := range seeds {
+		base := search.SearchParams{Ajax: true, Page: 1, Limit: limit}
+		for key, value := range seed {
+			if err := base.Set(key, value); err != nil {
+				return nil, fmt.Errorf("plan %s: seed: %w", p.Name, err)
+			}
+		}
+		expanded := []search.SearchParams{base}
+		for _, g := range p.Generators {
+			values, err := g.values()
+			if err != nil {
+				return nil, fmt.Errorf("plan %s: %w", p.Name, err)
+			}
+			next := make([]search.SearchParams, 0, len(expanded)*len(values))
+			for _, params := range expanded {
+				for _, value := range values {
+					child := params
+					for key, v := range value {
+						if err := child.Set(key, v); err != nil {
+							return nil, fmt.Errorf("plan %s: generator %s: %w", p.Name, g.Type, err)
+						}
+					}
+					next = append(next, child)
+				}
+			}
+			expanded = next
+		}
+		queries = append(queries, expanded...)
+	}
+
+	for _, q := range queries {
+		if err := q.Validate(); err != nil {
+			return nil, fmt.Errorf("plan %s: invalid search: %w", p.Name, err)
+		}
+	}
+	if p.Limits.MaxQueries > 0 && len(queries) > p.Limits.MaxQueries {
+		queries = queries[:p.Limits.MaxQueries]
+	}
+	return queries, nil
+}
+
+// values returns the search parameters to set for each value the generator
+// produces.
+func (g Generator) values() ([]map[string]string, error) {
+	if g.Param == "" {
+		return nil, fmt.Errorf("%s generator requires a param", g.Type)
+	}
+	var values []string
+	var err error
+	switch g.Type {
+	case GeneratorAlphabet:
+		values, err = g.alphabetValues()
+	case GeneratorYears:
+		return g.yearValues()
+	case GeneratorValues:
+		if len(g.Values) == 0 {
+			return nil, fmt.Errorf("values generator for %s has no values", g.Param)
+		}
+		values = g.Values
+	default:
+		return nil, fmt.Errorf("unknown generator type %q", g.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	assignments := make([]map[string]string, len(values))
+	for i, v := range values {
+		assignments[i] = map[string]string{g.Param: v}
+	}
+	return assignments, nil
+}
+
+func (g Generator) alphabetValues() ([]string, error) {
+	letters := g.Letters
+	if letters == "" {
+		letters = defaultAlphabet
+	}
+	length := g.Length
+	if length == 0 {
+		length = 1
+	}
+	if length < 0 || length > 3 {
+		return nil, fmt.Errorf("alphabet length must be between 1 and 3, got %d", length)
+	}
+	suffix := "*"
+	if g.Suffix != nil {
+		suffix = *g.Suffix
+	}
+
+	prefixes := []string{""}
+	for range length {
+		next := make([]string, 0, len(prefixes)*len(letters))
+		for _, prefix := range prefixes {
+			for _, c := range letters {
+				next = append(next, prefix+string(c))
+			}
+		}
+		prefixes = next
+	}
+	values := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		values[i] = prefix + suffix
+	}
+	return values, nil
+}
+
+func (g Generator) yearValues() ([]map[string]string, error) {
+	from, to := g.From, g.To
+	if g.Last > 0 {
+		if from != 0 || to != 0 {
+			return nil, fmt.Errorf("years generator takes either last or from/to, not both")
+		}
+		to = time.Now().Year()
+		from = to - g.Last + 1
+	}
+	if from <= 0 || to < from {
+		return nil, fmt.Errorf("years range %d-%d is invalid", from, to)
+	}
+
+	width := g.Bucket
+	if width == 0 {
+		width = 1
+	}
+	filter, ok := bucketFilters[width]
+	if !ok {
+		return nil, fmt.Errorf("years bucket must be 1, 3, 7, 11, 21 or 51, got %d", g.Bucket)
+	}
+	step := g.Step
+	if step == 0 {
+		step = width
+	}
+	if step < width {
+		return nil, fmt.Errorf("years step %d is smaller than the bucket %d, so searches would overlap", step, width)
+	}
+
+	var values []map[string]string
+	for start := from; start <= to; start += step {
+		end := min(start+width-1, to)
+		for bucketStart := start; bucketStart <= end; {
+			w, wfilter := width, filter
+			if bucketStart+w-1 > end {
+				w, wfilter = widestBucket(end - bucketStart + 1)
+			}
+			value := map[string]string{g.Param: fmt.Sprint(bucketStart + w/2)}
+			if wfilter != "" {
+				value[g.Param+"filter"] = wfilter
+			}
+			values = append(values, value)
+			bucketStart += w
+		}
+	}
+	return values, nil
+}
+
+// widestBucket returns the widest bucket, and its filter, that fits in
+// years.
+func widestBucket(years int) (int, string) {
+	width := 1
+	for w := range bucketFilters {
+		if w <= years && w > width {
+			width = w
+		}
+	}
+	return width, bucketFilters[width]
+}
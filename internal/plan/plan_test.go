@@ -0,0 +1,205 @@
+package plan_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/plan"
+	"github.com/ChaseHampton/gofindag/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault_MatchesGenLoop(t *testing.T) {
+	queries, err := plan.Default().Expand(20)
+
+	require.NoError(t, err)
+	require.Len(t, queries, 26*26)
+	assert.Equal(t, "A*", *queries[0].LName)
+	assert.Equal(t, "A*", *queries[0].FName)
+	assert.Equal(t, "A*", *queries[1].LName)
+	assert.Equal(t, "B*", *queries[1].FName)
+	assert.Equal(t, "Z*", *queries[675].LName)
+	for _, q := range queries {
+		assert.Equal(t, time.Now().Year(), q.DeathYear)
+		assert.Equal(t, 20, q.Limit)
+		assert.Equal(t, 1, q.Page)
+	}
+}
+
+func TestPlan_Expand_GeneratorsAndLimits(t *testing.T) {
+	suffix := ""
+	p := &plan.Plan{
+		Name:   "test",
+		Limits: plan.Limits{MaxQueries: 5, PageSize: 50},
+		Seeds:  []map[string]string{{"isVeteran": "true"}, {"famous": "true"}},
+		Generators: []plan.Generator{
+			{Type: plan.GeneratorYears, Param: "birthyear", From: 1900, To: 1910, Step: 5},
+			{Type: plan.GeneratorAlphabet, Param: "middleName", Letters: "XY", Suffix: &suffix},
+		},
+	}
+
+	queries, err := p.Expand(20)
+
+	require.NoError(t, err)
+	require.Len(t, queries, 5)
+	assert.Equal(t, 1900, queries[0].BirthYear)
+	assert.Equal(t, "X", *queries[0].MName)
+	assert.Equal(t, "Y", *queries[1].MName)
+	assert.Equal(t, 1905, queries[2].BirthYear)
+	assert.True(t, queries[4].IsVeteran)
+	assert.Equal(t, 50, queries[0].Limit)
+}
+
+func TestYearSweep(t *testing.T) {
+	p, err := plan.YearSweep("birthyear", 1900, 1920, 0, 7)
+	require.NoError(t, err)
+
+	queries, err := p.Expand(20)
+
+	require.NoError(t, err)
+	require.Len(t, queries, 3)
+	assert.Equal(t, []int{1903, 1910, 1917}, []int{queries[0].BirthYear, queries[1].BirthYear, queries[2].BirthYear})
+	assert.Equal(t, search.YearWithin3, queries[0].BirthYearFilter)
+	assert.False(t, p.Refresh)
+}
+
+func TestYearSweep_ClampsLastBucket(t *testing.T) {
+	p, err := plan.YearSweep("birthyear", 1900, 1910, 0, 7)
+	require.NoError(t, err)
+
+	queries, err := p.Expand(20)
+
+	require.NoError(t, err)
+	require.Len(t, queries, 3)
+	assert.Equal(t, 1903, queries[0].BirthYear)
+	assert.Equal(t, search.YearWithin3, queries[0].BirthYearFilter)
+	// 1907-1910 is covered by 1907-1909 and 1910 rather than 1907-1913
+	assert.Equal(t, 1908, queries[1].BirthYear)
+	assert.Equal(t, search.YearWithin1, queries[1].BirthYearFilter)
+	assert.Equal(t, 1910, queries[2].BirthYear)
+	assert.Equal(t, search.YearExact, queries[2].BirthYearFilter)
+}
+
+func TestYearSweep_LastYears(t *testing.T) {
+	p, err := plan.YearSweep("deathyear", 0, 0, 5, 0)
+	require.NoError(t, err)
+
+	queries, err := p.Expand(20)
+
+	require.NoError(t, err)
+	require.Len(t, queries, 5)
+	current := time.Now().Year()
+	assert.Equal(t, current-4, queries[0].DeathYear)
+	assert.Equal(t, current, queries[4].DeathYear)
+	assert.Equal(t, search.YearExact, queries[4].DeathYearFilter)
+}
+
+func TestYearSweep_Invalid(t *testing.T) {
+	_, err := plan.YearSweep("deathyear", 1900, 1910, 0, 4)
+	assert.Error(t, err, "bucket widths must match a year filter")
+
+	_, err = plan.YearSweep("deathyear", 1900, 1910, 3, 0)
+	assert.Error(t, err, "last cannot be combined with a fixed range")
+
+	_, err = plan.YearSweep("lastName", 1900, 1910, 0, 0)
+	assert.Error(t, err)
+
+	overlapping := &plan.Plan{Name: "overlap", Generators: []plan.Generator{
+		{Type: plan.GeneratorYears, Param: "deathyear", From: 1900, To: 1920, Bucket: 7, Step: 3},
+	}}
+	assert.Error(t, overlapping.Validate(), "a step smaller than the bucket overlaps searches")
+}
+
+func TestCemeteries(t *testing.T) {
+	p, err := plan.Cemeteries("101, 202,")
+	require.NoError(t, err)
+
+	queries, err := p.Expand(20)
+
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	assert.Equal(t, "cemetery:101", queries[0].Scope())
+	assert.Equal(t, "cemetery:202", queries[1].Scope())
+	assert.Nil(t, queries[0].LName)
+
+	_, err = plan.Cemeteries("101,abc")
+	assert.Error(t, err)
+	_, err = plan.Cemeteries(" ")
+	assert.Error(t, err)
+}
+
+func TestLimits_DeadlineDuration(t *testing.T) {
+	d, err := plan.Limits{}.DeadlineDuration()
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	d, err = plan.Limits{Deadline: "90m"}.DeadlineDuration()
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	p := &plan.Plan{Name: "bad", Limits: plan.Limits{Deadline: "soon"}}
+	assert.Error(t, p.Validate())
+}
+
+func TestPlan_Expand_RejectsInvalidSearches(t *testing.T) {
+	p := &plan.Plan{
+		Name:       "bad",
+		Generators: []plan.Generator{{Type: plan.GeneratorValues, Param: "locationId", Values: []string{"usa"}}},
+	}
+
+	_, err := p.Expand(20)
+
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"name": "file",
+		"seeds": [{"deathyear": "2000"}],
+		"generators": [{"type": "alphabet", "param": "lastName", "length": 2}]
+	}`), 0o644))
+
+	p, err := plan.Load(path)
+	require.NoError(t, err)
+	queries, err := p.Expand(20)
+	require.NoError(t, err)
+	assert.Len(t, queries, 676)
+	assert.Equal(t, "AB*", *queries[1].LName)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "typo", "generatorz": []}`), 0o644))
+	_, err = plan.Load(path)
+	assert.Error(t, err, "unknown fields should be rejected")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "x", "generators": [{"type": "months", "param": "deathyear"}]}`), 0o644))
+	_, err = plan.Load(path)
+	assert.Error(t, err)
+}
+
+func TestLoad_ExamplePlan(t *testing.T) {
+	p, err := plan.Load("../../plans/example.json")
+	require.NoError(t, err)
+
+	queries, err := p.Expand(20)
+
+	require.NoError(t, err)
+	assert.Len(t, queries, 3*26)
+}
+
+func TestLoad_Priority(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "urgent", "priority": 10, "seeds": [{"cemeteryId": "42"}]}`), 0o644))
+
+	p, err := plan.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 10, p.Priority)
+
+	// plans without a priority keep the definition they were registered with
+	definition, err := plan.Default().Definition()
+	require.NoError(t, err)
+	assert.NotContains(t, definition, "priority")
+}
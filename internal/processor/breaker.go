@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker pauses the whole crawl once the site has refused several
+// requests in a row, rather than letting every worker burn through its
+// pages' retries. After the cooldown the breaker is half open: the next
+// refusal reopens it straight away and a success closes it.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	refusals  int
+	openUntil time.Time
+}
+
+// NewCircuitBreaker returns a breaker that opens after threshold
+// consecutive refusals. A threshold of 0 disables it.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Wait blocks while the breaker is open.
+func (b *CircuitBreaker) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		wait := time.Until(b.openUntil)
+		b.mu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Record updates the breaker with the result of a page request. Failures
+// that are not refusals leave it unchanged.
+func (b *CircuitBreaker) Record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.refusals = 0
+		return
+	}
+	if !IsRefusal(err) {
+		return
+	}
+	b.refusals++
+	if b.refusals < b.threshold || time.Now().Before(b.openUntil) {
+		return
+	}
+	b.openUntil = time.Now().Add(b.cooldown)
+	b.refusals = b.threshold - 1
+	fmt.Printf("Site refused %d requests in a row, pausing the crawl for %v: %v\n", b.threshold, b.cooldown, err)
+}
+
+// Open reports whether the breaker is currently pausing the crawl.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.openUntil)
+}
@@ -0,0 +1,50 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/db"
+)
+
+// Budget bounds how much of a search a single collection fetches. Zero
+// values are unlimited.
+type Budget struct {
+	MaxPages   int
+	MaxRecords int
+	// Deadline is measured from when the collection is seeded; pages still
+	// open after it are not fetched.
+	Deadline time.Duration
+}
+
+// Pages returns how many of a search's pages fit in the budget when the site
+// serves at most pageCap pages of limit records each, and the reason when
+// that is fewer than the search has.
+func (b Budget) Pages(pages int, limit int, pageCap int) (int, string) {
+	allowed, reason := pages, ""
+	if allowed > pageCap {
+		allowed, reason = pageCap, db.TruncatedPageCap
+	}
+	if b.MaxPages > 0 && allowed > b.MaxPages {
+		allowed, reason = b.MaxPages, db.TruncatedMaxPages
+	}
+	if b.MaxRecords > 0 {
+		if recordPages := pageCount(b.MaxRecords, limit); allowed > recordPages {
+			allowed, reason = recordPages, db.TruncatedMaxRecords
+		}
+	}
+	return allowed, reason
+}
+
+// orDefault fills in any limits b leaves unset from fallback.
+func (b Budget) orDefault(fallback Budget) Budget {
+	if b.MaxPages == 0 {
+		b.MaxPages = fallback.MaxPages
+	}
+	if b.MaxRecords == 0 {
+		b.MaxRecords = fallback.MaxRecords
+	}
+	if b.Deadline == 0 {
+		b.Deadline = fallback.Deadline
+	}
+	return b
+}
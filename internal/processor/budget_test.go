@@ -0,0 +1,33 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/db"
+	"github.com/ChaseHampton/gofindag/internal/processor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget_Pages(t *testing.T) {
+	cases := map[string]struct {
+		budget processor.Budget
+		pages  int
+		want   int
+		reason string
+	}{
+		"fits":        {budget: processor.Budget{}, pages: 10, want: 10},
+		"page cap":    {budget: processor.Budget{}, pages: 600, want: 500, reason: db.TruncatedPageCap},
+		"max pages":   {budget: processor.Budget{MaxPages: 50}, pages: 600, want: 50, reason: db.TruncatedMaxPages},
+		"max records": {budget: processor.Budget{MaxPages: 50, MaxRecords: 110}, pages: 600, want: 6, reason: db.TruncatedMaxRecords},
+		"under both":  {budget: processor.Budget{MaxPages: 50, MaxRecords: 1000}, pages: 20, want: 20},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, reason := tc.budget.Pages(tc.pages, 20, 500)
+
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ChaseHampton/gofindag/internal/config"
 	"github.com/ChaseHampton/gofindag/internal/db"
 	"github.com/ChaseHampton/gofindag/internal/duplicates"
+	"github.com/ChaseHampton/gofindag/internal/search"
 )
 
 type MemorialProcessor struct {
@@ -40,7 +41,10 @@ func (mp *MemorialProcessor) ProcessMemorials(ctx context.Context, membatch Memo
 		return nil
 	}
 
-	new, seen := mp.memorialCache.FilterMemorials(membatch.Memorials)
+	new, seen := membatch.Memorials, []search.Memorial(nil)
+	if !membatch.Upsert {
+		new, seen = mp.memorialCache.FilterMemorials(membatch.Memorials)
+	}
 	dupechan := mp.dp.Channel()
 	fmt.Printf("Adding %d new memorials and removing %d seen memorials.\n", len(new), len(seen))
 	for _, record := range seen {
@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/ChaseHampton/gofindag/internal/search"
+)
+
+// Outcome is what a search response turned out to contain.
+type Outcome string
+
+const (
+	OutcomeOK Outcome = "ok"
+	// OutcomeHTML is an HTML page, usually an error or bot check, where JSON
+	// was expected.
+	OutcomeHTML Outcome = "html"
+	// OutcomeLoginWall is a response asking us to sign in.
+	OutcomeLoginWall Outcome = "login_wall"
+	// OutcomeRefused is a 403, or JSON whose responseCode is not 200.
+	OutcomeRefused Outcome = "refused"
+	// OutcomeMalformed is a body that is neither JSON nor HTML.
+	OutcomeMalformed Outcome = "malformed"
+	// OutcomeTooLarge is a body over the configured maximum size.
+	OutcomeTooLarge Outcome = "too_large"
+)
+
+// Refusal reports whether the site declined to serve the request at all,
+// as opposed to something being wrong with one page. Refusals are not
+// retried per page; they trip the crawl-wide circuit breaker instead.
+func (o Outcome) Refusal() bool {
+	return o == OutcomeHTML || o == OutcomeLoginWall || o == OutcomeRefused
+}
+
+// IsRefusal reports whether err is a request the site refused.
+func IsRefusal(err error) bool {
+	var reqErr *RequestError
+	return errors.As(err, &reqErr) && reqErr.Outcome.Refusal()
+}
+
+// DecodeSearchResponse unmarshals a successful search response that has
+// been read in full. See DecodeSearchStream.
+func DecodeSearchResponse(resp *client.Response) (*search.SearchResponse, error) {
+	var records []search.Memorial
+	searchresp, err := DecodeSearchStream(resp, bytes.NewReader(resp.Body), func(m search.Memorial) error {
+		records = append(records, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	searchresp.Records = records
+	return searchresp, nil
+}
+
+// DecodeSearchStream decodes a successful search response from body as it
+// arrives, passing each memorial to fn. Responses that are not usable search
+// results are returned as a *RequestError whose Outcome says why: refusals
+// and read errors are retryable, malformed and oversized bodies are not.
+// Memorials already passed to fn should be discarded if an error is
+// returned, since a refusal may only be apparent once the body is read.
+func DecodeSearchStream(resp *client.Response, body io.Reader, fn func(search.Memorial) error) (*search.SearchResponse, error) {
+	failed := func(class FailureClass, outcome Outcome, err error) error {
+		return &RequestError{Class: class, Outcome: outcome, StatusCode: resp.StatusCode, Attempts: 1, Err: err}
+	}
+	br := bufio.NewReader(body)
+	if isHTML(resp, br) {
+		snippet, _ := io.ReadAll(io.LimitReader(br, 200))
+		return nil, failed(FailureRetryable, OutcomeHTML, fmt.Errorf("HTML response: %s", snippet))
+	}
+
+	var fnErr error
+	searchresp, err := search.DecodeResponse(br, func(m search.Memorial) error {
+		fnErr = fn(m)
+		return fnErr
+	})
+	switch {
+	case err == nil:
+	case fnErr != nil:
+		return nil, fnErr
+	case isMalformed(err):
+		return nil, failed(FailurePermanent, OutcomeMalformed, fmt.Errorf("malformed search response: %w", err))
+	default:
+		return nil, readFailure(resp, err)
+	}
+
+	if searchresp.AncestryLogin {
+		return nil, failed(FailureRetryable, OutcomeLoginWall, fmt.Errorf("search response requires login"))
+	}
+	if searchresp.ResponseCode != 0 && searchresp.ResponseCode != http.StatusOK {
+		return nil, failed(FailureRetryable, OutcomeRefused, fmt.Errorf("search response code %d", searchresp.ResponseCode))
+	}
+	return searchresp, nil
+}
+
+// isHTML reports whether the response is a web page, going by its
+// Content-Type or, failing that, its first non-blank byte.
+func isHTML(resp *client.Response, br *bufio.Reader) bool {
+	if strings.Contains(resp.Headers.Get("Content-Type"), "text/html") {
+		return true
+	}
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return false
+		}
+		if !unicode.IsSpace(rune(b)) {
+			br.UnreadByte()
+			return b == '<'
+		}
+	}
+}
+
+// readFailure classifies an error reading the body of a successful response.
+func readFailure(resp *client.Response, err error) *RequestError {
+	if errors.Is(err, client.ErrBodyTooLarge) {
+		return &RequestError{Class: FailurePermanent, Outcome: OutcomeTooLarge, StatusCode: resp.StatusCode, Attempts: 1, Err: err}
+	}
+	return &RequestError{Class: FailureRetryable, StatusCode: resp.StatusCode, Attempts: 1, Err: fmt.Errorf("failed to read response body: %w", err)}
+}
+
+// isMalformed reports whether a body that was read could not be parsed. A
+// body that ends early is not malformed: the connection most likely dropped
+// mid-response, so it is left to readFailure and retried.
+func isMalformed(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return false
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, search.ErrMalformed)
+}
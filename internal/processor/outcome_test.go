@@ -0,0 +1,113 @@
+package processor_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/ChaseHampton/gofindag/internal/config"
+	"github.com/ChaseHampton/gofindag/internal/processor"
+	"github.com/ChaseHampton/gofindag/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func body(contentType, b string) *client.Response {
+	return &client.Response{StatusCode: http.StatusOK, Headers: http.Header{"Content-Type": {contentType}}, Body: []byte(b)}
+}
+
+func TestDecodeSearchResponse(t *testing.T) {
+	cases := map[string]struct {
+		resp    *client.Response
+		outcome processor.Outcome
+		class   processor.FailureClass
+	}{
+		"html content type": {resp: body("text/html; charset=utf-8", "Access denied"), outcome: processor.OutcomeHTML, class: processor.FailureRetryable},
+		"html body":         {resp: body("application/json", "\n<!DOCTYPE html><html></html>"), outcome: processor.OutcomeHTML, class: processor.FailureRetryable},
+		"login wall":        {resp: body("application/json", `{"ancestryLogin":true}`), outcome: processor.OutcomeLoginWall, class: processor.FailureRetryable},
+		"response code":     {resp: body("application/json", `{"responseCode":403}`), outcome: processor.OutcomeRefused, class: processor.FailureRetryable},
+		"malformed":         {resp: body("application/json", `{"total":]`), outcome: processor.OutcomeMalformed, class: processor.FailurePermanent},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := processor.DecodeSearchResponse(tc.resp)
+
+			var reqErr *processor.RequestError
+			require.ErrorAs(t, err, &reqErr)
+			assert.Equal(t, tc.outcome, reqErr.Outcome)
+			assert.Equal(t, tc.class, reqErr.Class)
+			assert.Equal(t, tc.outcome.Refusal(), processor.IsRefusal(err))
+		})
+	}
+
+	resp, err := processor.DecodeSearchResponse(body("application/json", `{"total":2,"responseCode":200}`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Total)
+}
+
+func TestDecodeSearchStream_TruncatedBodyIsRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "500")
+		w.Write([]byte(`{"total":2,"records":[{"memorialId":1`))
+	}))
+	defer srv.Close()
+
+	c, err := client.NewPageClient(&config.HTTPConfig{Timeout: 5 * time.Second, MaxBodyBytes: 1 << 20}, nil)
+	require.NoError(t, err)
+	resp, stream, err := c.Stream(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	_, err = processor.DecodeSearchStream(resp, stream, func(search.Memorial) error { return nil })
+
+	var reqErr *processor.RequestError
+	require.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, processor.FailureRetryable, reqErr.Class)
+	assert.NotEqual(t, processor.OutcomeMalformed, reqErr.Outcome)
+
+	// a body that simply stops is treated the same way
+	_, err = processor.DecodeSearchResponse(body("application/json", `{"total":`))
+	require.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, processor.FailureRetryable, reqErr.Class)
+}
+
+func TestCircuitBreaker_OpensAfterConsecutiveRefusals(t *testing.T) {
+	breaker := processor.NewCircuitBreaker(3, 50*time.Millisecond)
+	refusal := &processor.RequestError{Class: processor.FailureRetryable, Outcome: processor.OutcomeLoginWall, Err: errors.New("login")}
+
+	breaker.Record(refusal)
+	breaker.Record(refusal)
+	breaker.Record(nil)
+	breaker.Record(refusal)
+	breaker.Record(errors.New("timeout"))
+	breaker.Record(refusal)
+	assert.False(t, breaker.Open(), "a success resets the count and other failures do not add to it")
+
+	breaker.Record(refusal)
+	assert.True(t, breaker.Open())
+
+	start := time.Now()
+	require.NoError(t, breaker.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	assert.False(t, breaker.Open())
+
+	// half open: one more refusal pauses again
+	breaker.Record(refusal)
+	assert.True(t, breaker.Open())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, breaker.Wait(ctx), context.Canceled)
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	breaker := processor.NewCircuitBreaker(0, time.Hour)
+	for range 10 {
+		breaker.Record(&processor.RequestError{Outcome: processor.OutcomeRefused, Err: errors.New("refused")})
+	}
+	assert.False(t, breaker.Open())
+}
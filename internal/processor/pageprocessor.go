@@ -80,7 +80,7 @@ func (pp *PageProcessor) handlePageUpdate(ctx context.Context, update PageUpdate
 			return
 		}
 		defer tx.Rollback()
-		err = db.MarkPageCollected(ctx, update.PageId, tx)
+		err = db.MarkPageCollected(ctx, update.collected(), tx)
 		if err != nil {
 			fmt.Println(fmt.Errorf("failed to mark page as collected: %w", err))
 			return
@@ -90,7 +90,7 @@ func (pp *PageProcessor) handlePageUpdate(ctx context.Context, update PageUpdate
 			return
 		}
 	case PageFailed:
-		err := pp.dbWriter.SetPageFailed(ctx, update.PageId)
+		err := pp.dbWriter.SetPageFailed(ctx, update.failed())
 		if err != nil {
 			fmt.Println(fmt.Errorf("failed to set page as failed: %w", err))
 			return
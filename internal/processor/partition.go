@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"strings"
+
+	"github.com/ChaseHampton/gofindag/internal/search"
+)
+
+const partitionAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Partitioner splits a search whose results cannot all be paged through into
+// narrower child searches that together cover the same result set.
+type Partitioner struct {
+	maxPrefixLength int
+}
+
+func NewPartitioner(maxPrefixLength int) *Partitioner {
+	return &Partitioner{maxPrefixLength: maxPrefixLength}
+}
+
+// Split returns the child searches for params and the name of the field they
+// were split on. ok is false when params cannot be narrowed any further.
+//
+// Last names are narrowed first, then first names. A wildcard prefix such as
+// "AB*" becomes the exact name "AB" plus "ABA*" through "ABZ*". The search
+// only supports trailing wildcards, so there is no child for names that
+// continue with anything other than A-Z, such as "AB'C" or "AB-C"; those
+// records are not reached, and the gap is checked once the children have
+// been probed (see Processor.checkCoverage).
+//
+// Only names are split. Year and location are fixed by the plan that seeded
+// the search (e.g. a year sweep's buckets or a location seed), so they are
+// left to the plan rather than narrowed here.
+func (pt *Partitioner) Split(params search.SearchParams) ([]search.SearchParams, string, bool) {
+	if children, ok := pt.splitName(params, params.LName, func(sp *search.SearchParams, v *string) { sp.LName = v }); ok {
+		return children, "lastName", true
+	}
+	if children, ok := pt.splitName(params, params.FName, func(sp *search.SearchParams, v *string) { sp.FName = v }); ok {
+		return children, "firstName", true
+	}
+	return nil, "", false
+}
+
+func (pt *Partitioner) splitName(params search.SearchParams, name *string, set func(*search.SearchParams, *string)) ([]search.SearchParams, bool) {
+	prefix := ""
+	if name != nil {
+		if !strings.HasSuffix(*name, "*") {
+			return nil, false
+		}
+		prefix = strings.TrimSuffix(*name, "*")
+		if strings.Contains(prefix, "*") || len(prefix) >= pt.maxPrefixLength {
+			return nil, false
+		}
+	}
+
+	children := make([]search.SearchParams, 0, len(partitionAlphabet)+1)
+	if prefix != "" {
+		exact := prefix
+		child := params
+		set(&child, &exact)
+		children = append(children, child)
+	}
+	for _, c := range partitionAlphabet {
+		value := prefix + string(c) + "*"
+		child := params
+		set(&child, &value)
+		children = append(children, child)
+	}
+	return children, true
+}
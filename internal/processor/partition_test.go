@@ -0,0 +1,81 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/processor"
+	"github.com/ChaseHampton/gofindag/internal/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func names(children []search.SearchParams, get func(search.SearchParams) *string) []string {
+	out := make([]string, 0, len(children))
+	for _, c := range children {
+		if v := get(c); v != nil {
+			out = append(out, *v)
+		}
+	}
+	return out
+}
+
+func TestPartitioner_Split_NoLastName(t *testing.T) {
+	pt := processor.NewPartitioner(3)
+
+	children, field, ok := pt.Split(search.SearchParams{Limit: 20, DeathYear: 2025})
+
+	assert.True(t, ok)
+	assert.Equal(t, "lastName", field)
+	assert.Len(t, children, 26)
+	lnames := names(children, func(sp search.SearchParams) *string { return sp.LName })
+	assert.Equal(t, "A*", lnames[0])
+	assert.Equal(t, "Z*", lnames[25])
+	for _, c := range children {
+		assert.Equal(t, 2025, c.DeathYear, "children should keep the parent's filters")
+	}
+}
+
+func TestPartitioner_Split_ExtendsPrefixWithExactMatch(t *testing.T) {
+	pt := processor.NewPartitioner(3)
+	lname := "AB*"
+	fname := "C*"
+
+	children, field, ok := pt.Split(search.SearchParams{LName: &lname, FName: &fname})
+
+	assert.True(t, ok)
+	assert.Equal(t, "lastName", field)
+	assert.Len(t, children, 27)
+	lnames := names(children, func(sp search.SearchParams) *string { return sp.LName })
+	assert.Equal(t, []string{"AB", "ABA*", "ABB*"}, lnames[:3])
+	assert.Equal(t, "C*", *children[0].FName)
+}
+
+func TestPartitioner_Split_FallsBackToFirstName(t *testing.T) {
+	pt := processor.NewPartitioner(3)
+	maxed := "ABC*"
+	fname := "J*"
+
+	children, field, ok := pt.Split(search.SearchParams{LName: &maxed, FName: &fname})
+
+	assert.True(t, ok)
+	assert.Equal(t, "firstName", field)
+	fnames := names(children, func(sp search.SearchParams) *string { return sp.FName })
+	assert.Equal(t, []string{"J", "JA*"}, fnames[:2])
+	assert.Equal(t, "ABC*", *children[1].LName)
+
+	exact := "Smith"
+	children, field, ok = pt.Split(search.SearchParams{LName: &exact, FName: &fname})
+
+	assert.True(t, ok)
+	assert.Equal(t, "firstName", field)
+	assert.Equal(t, "Smith", *children[0].LName)
+}
+
+func TestPartitioner_Split_Exhausted(t *testing.T) {
+	pt := processor.NewPartitioner(3)
+	lname := "Smith"
+	fname := "John"
+
+	_, _, ok := pt.Split(search.SearchParams{LName: &lname, FName: &fname})
+
+	assert.False(t, ok)
+}
@@ -3,13 +3,12 @@ package processor
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/url"
-	"strconv"
-	"strings"
+	"io"
 	"time"
 
+	"github.com/ChaseHampton/gofindag/internal/archive"
 	"github.com/ChaseHampton/gofindag/internal/client"
 	"github.com/ChaseHampton/gofindag/internal/config"
 	"github.com/ChaseHampton/gofindag/internal/db"
@@ -19,14 +18,13 @@ import (
 type Processor struct {
 	client         *client.Client
 	MaxConcurrency int
-	retryAttmpts   int
-	retryDelay     time.Duration
+	retryPolicy    RetryPolicy
 	PageDelay      time.Duration
 	maxPages       int
 	baseURL        string
-	httpConfig     *config.HTTPConfig
 	config         *config.Config
 	memproc        *MemorialProcessor
+	partitioner    *Partitioner
 }
 
 type SearchPage struct {
@@ -38,63 +36,304 @@ type SearchPage struct {
 
 type PageHandler func(page *SearchPage, db *db.DbWriter) error
 
-func NewProcessor(client *client.Client, cfg config.ProcessorConfig, http *config.HTTPConfig, config *config.Config, memproc *MemorialProcessor) *Processor {
+// NewProcessor returns a Processor that fetches every page through client,
+// which should be a long-lived page client shared by all workers.
+func NewProcessor(client *client.Client, cfg config.ProcessorConfig, config *config.Config, memproc *MemorialProcessor) *Processor {
 	return &Processor{
 		client:         client,
 		MaxConcurrency: cfg.MaxConcurrency,
-		retryAttmpts:   cfg.RetryAttempts,
-		retryDelay:     cfg.RetryDelay,
+		retryPolicy:    NewExponentialBackoff(cfg),
 		PageDelay:      cfg.PageDelay,
 		maxPages:       cfg.MaxPages,
 		baseURL:        cfg.BaseURL,
-		httpConfig:     http,
 		config:         config,
 		memproc:        memproc,
+		partitioner:    NewPartitioner(cfg.MaxPrefixLength),
 	}
 }
 
-func (p *Processor) CollectionStart(ctx context.Context, dbw *db.DbWriter, searchParams *search.SearchParams) error {
-	params := *searchParams
-	url := p.buildSearchURL(*searchParams)
-	pageBatch := p.config.ProcessorConfig.BatchSize
+// CollectionStart seeds the pages for a search. Searches whose results exceed
+// what can be paged through are split into narrower child searches until each
+// one fits, and every node of that tree is recorded in QueryPartitions.
+//
+// Seeding is idempotent: searches are matched on their QueryKey, an
+// incomplete collection is resumed by inserting only its missing pages, and a
+// completed one is skipped unless opts.Refresh or opts.Incremental is set.
+func (p *Processor) CollectionStart(ctx context.Context, dbw *db.DbWriter, searchParams *search.SearchParams, opts SeedOptions) error {
+	if err := searchParams.Validate(); err != nil {
+		return fmt.Errorf("invalid search parameters: %w", err)
+	}
+	if opts.Incremental && searchParams.OrderBy == "" {
+		sorted := *searchParams
+		sorted.OrderBy = p.config.ProcessorConfig.IncrementalOrderBy
+		searchParams = &sorted
+	}
+	queryKey := searchParams.QueryKey()
+	complete, err := dbw.IsQueryComplete(ctx, queryKey)
+	if err != nil {
+		return err
+	}
+	if complete && !opts.Refresh && !opts.Incremental {
+		fmt.Printf("Skipping completed search: %s\n", queryKey)
+		return nil
+	}
+	// A refresh of a completed search gets a new partition tree rather than
+	// resuming the old one.
+	return p.startPartition(ctx, dbw, *searchParams, opts, nil, 0, !complete)
+}
+
+func (p *Processor) startPartition(ctx context.Context, dbw *db.DbWriter, params search.SearchParams, opts SeedOptions, parentId *int, depth int, reuse bool) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("operation cancelled: %w", ctx.Err())
+	default:
+	}
+	params.Page = 1
+	params.Skip = 0
+	url := p.buildSearchURL(params)
 	if url == "" {
 		return fmt.Errorf("failed to build search URL")
 	}
-	collectParams := db.GetNewCollectionParams(searchParams.Limit, url)
-	collectionId, err := dbw.StartCollection(ctx, collectParams)
+	queryKey := params.QueryKey()
+
+	if reuse {
+		node, err := dbw.FindQueryPartition(ctx, parentId, queryKey)
+		if err != nil {
+			return err
+		}
+		if node != nil {
+			if node.SplitOn.Valid {
+				if children, _, ok := p.partitioner.Split(params); ok {
+					if err := p.startChildren(ctx, dbw, children, opts, node.PartitionId, depth); err != nil {
+						return err
+					}
+					return p.checkCoverage(ctx, dbw, node.PartitionId, queryKey)
+				}
+			}
+			if node.CollectionId.Valid {
+				status, err := dbw.GetCollectionStatus(ctx, int(node.CollectionId.Int32))
+				if err != nil {
+					return err
+				}
+				if status.IsComplete && status.CoverageSuspect {
+					return p.recollect(ctx, dbw, params, opts, node.PartitionId, status.CollectionId)
+				}
+				return p.resumeCollection(ctx, dbw, params, status)
+			}
+			if node.TotalRecords == 0 {
+				return nil
+			}
+		}
+	}
+
+	searchresp, err := p.probe(ctx, url)
+	if err != nil {
+		return err
+	}
+	totalRecords := searchresp.Total
+
+	partition := db.NewPartitionParams(parentId, url, queryKey, totalRecords, depth)
+	if searchresp.TooMany || totalRecords > params.Limit*p.maxPages {
+		// Sorted incremental searches list the newest records first and stop
+		// once pages are older than the watermark, so they are not split.
+		sortedIncremental := opts.Incremental && params.OrderBy != ""
+		if !sortedIncremental && depth < p.config.ProcessorConfig.MaxPartitionDepth {
+			if children, field, ok := p.partitioner.Split(params); ok {
+				partition.SplitOn = sql.NullString{String: field, Valid: true}
+				partitionId, err := dbw.InsertQueryPartition(ctx, partition)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Partitioning search into %d searches on %s\n", len(children), field)
+				if err := p.startChildren(ctx, dbw, children, opts, partitionId, depth); err != nil {
+					return err
+				}
+				return p.checkCoverage(ctx, dbw, partitionId, queryKey)
+			}
+		}
+		if !sortedIncremental {
+			fmt.Println(fmt.Errorf("search cannot be partitioned further\nwill only reach %d of %d records", params.Limit*p.maxPages, totalRecords))
+			partition.IsTruncated = true
+		}
+	}
+
+	if totalRecords == 0 {
+		_, err := dbw.InsertQueryPartition(ctx, partition)
+		return err
+	}
+
+	existing, err := dbw.FindCollection(ctx, queryKey)
 	if err != nil {
-		return fmt.Errorf("failed to start collection: %w", err)
+		return err
+	}
+	if existing != nil && (!existing.IsComplete || (!opts.Refresh && !opts.Incremental && !existing.CoverageSuspect)) {
+		partition.CollectionId = sql.NullInt32{Int32: int32(existing.CollectionId), Valid: true}
+		if _, err := dbw.InsertQueryPartition(ctx, partition); err != nil {
+			return err
+		}
+		return p.resumeCollection(ctx, dbw, params, existing)
 	}
 
+	collectionId, pages, err := p.newCollection(ctx, dbw, params, url, opts, totalRecords)
+	if err != nil {
+		return err
+	}
+	partition.CollectionId = sql.NullInt32{Int32: int32(collectionId), Valid: true}
+	if _, err := dbw.InsertQueryPartition(ctx, partition); err != nil {
+		return err
+	}
+	return p.seedPages(ctx, dbw, params, collectionId, 1, pages, nil)
+}
+
+// probe requests the first page of a search to find out how large it is.
+func (p *Processor) probe(ctx context.Context, url string) (*search.SearchResponse, error) {
 	response, err := p.makeRequestWithRetry(ctx, url)
 	if err != nil {
-		return fmt.Errorf("failed to get search page %d: %w", 1, err)
+		return nil, fmt.Errorf("failed to get search page %d: %w", 1, err)
 	}
 
-	var searchresp search.SearchResponse
-	if err := json.Unmarshal(response.Body, &searchresp); err != nil {
-		return fmt.Errorf("failed to unmarshal search response: %w", err)
+	searchresp, err := DecodeSearchResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search page %d: %w", 1, err)
 	}
+	fmt.Printf("Search URL: %s\nTotal Records: %d\n", url, searchresp.Total)
+	return searchresp, nil
+}
 
+// newCollection starts a collection for the search and returns it along with
+// how many of its pages fit in the collection's budget.
+func (p *Processor) newCollection(ctx context.Context, dbw *db.DbWriter, params search.SearchParams, url string, opts SeedOptions, totalRecords int) (int, int, error) {
+	collectParams := db.GetNewCollectionParams(params.Limit, url, params.QueryKey(), params.Scope(), opts.PlanId, totalRecords)
+	collectParams.Priority = opts.Priority
+	if opts.Incremental {
+		since, err := dbw.GetQueryWatermark(ctx, collectParams.QueryKey)
+		if err != nil {
+			return 0, 0, err
+		}
+		collectParams.Mode = db.CollectionModeIncremental
+		collectParams.Since = since
+	}
+
+	budget := opts.Budget.orDefault(p.defaultBudget())
+	pages, reason := budget.Pages(collectParams.TotalPages, params.Limit, p.maxPages)
+	maxPages, _ := budget.Pages(p.maxPages, params.Limit, p.maxPages)
+	collectParams.TotalPages = pages
+	collectParams.MaxPages = sql.NullInt32{Int32: int32(maxPages), Valid: true}
+	collectParams.MaxRecords = sql.NullInt32{Int32: int32(budget.MaxRecords), Valid: budget.MaxRecords > 0}
+	collectParams.Deadline = sql.NullTime{Time: time.Now().Add(budget.Deadline), Valid: budget.Deadline > 0}
+	collectParams.TruncatedReason = sql.NullString{String: reason, Valid: reason != ""}
+	if reason != "" {
+		fmt.Printf("Collection limited to %d of %d pages (%s)\n", pages, pageCount(totalRecords, params.Limit), reason)
+	}
+
+	collectionId, err := dbw.StartCollection(ctx, collectParams)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to start collection: %w", err)
+	}
 	fmt.Printf("Collection started with ID: %d\n", collectionId)
+	return collectionId, pages, nil
+}
 
-	totalRecords := searchresp.Total
-	fmt.Printf("Search URL: %s\nTotal Records: %d\n", url, totalRecords)
-	if totalRecords > p.config.ProcessorConfig.BatchSize*p.config.ProcessorConfig.MaxPages {
-		fmt.Println(fmt.Errorf("total records will suppose max page limit\nwill only insert %d pages", p.config.ProcessorConfig.MaxPages))
+func (p *Processor) defaultBudget() Budget {
+	return Budget{
+		MaxPages:   p.config.ProcessorConfig.CollectionMaxPages,
+		MaxRecords: p.config.ProcessorConfig.CollectionMaxRecords,
+		Deadline:   p.config.ProcessorConfig.CollectionDeadline,
+	}
+}
+
+// recollect seeds a fresh collection for a partition whose previous
+// collection drifted too far while it was fetched to be trusted.
+func (p *Processor) recollect(ctx context.Context, dbw *db.DbWriter, params search.SearchParams, opts SeedOptions, partitionId int, suspectId int) error {
+	fmt.Printf("Collection %d drifted while it was collected, re-crawling.\n", suspectId)
+	url := p.buildSearchURL(params)
+	searchresp, err := p.probe(ctx, url)
+	if err != nil {
+		return err
+	}
+	collectionId, pages, err := p.newCollection(ctx, dbw, params, url, opts, searchresp.Total)
+	if err != nil {
+		return err
 	}
+	if err := dbw.SetPartitionCollection(ctx, partitionId, collectionId, searchresp.Total); err != nil {
+		return err
+	}
+	return p.seedPages(ctx, dbw, params, collectionId, 1, pages, nil)
+}
+
+func (p *Processor) startChildren(ctx context.Context, dbw *db.DbWriter, children []search.SearchParams, opts SeedOptions, partitionId int, depth int) error {
+	var errs []error
+	for _, child := range children {
+		if err := p.startPartition(ctx, dbw, child, opts, &partitionId, depth+1, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
 
+// checkCoverage logs records a split search matched that none of its
+// children do. The database flags such partitions truncated.
+func (p *Processor) checkCoverage(ctx context.Context, dbw *db.DbWriter, partitionId int, queryKey string) error {
+	coverage, err := dbw.CheckPartitionCoverage(ctx, partitionId)
+	if err != nil {
+		return err
+	}
+	if coverage.ChildRecords < coverage.TotalRecords {
+		fmt.Printf("Partitions of %s only cover %d of %d records; names continuing with characters other than A-Z will be missed\n",
+			queryKey, coverage.ChildRecords, coverage.TotalRecords)
+	}
+	return nil
+}
+
+// resumeCollection inserts whichever of the collection's pages were not
+// seeded before, e.g. because a previous run stopped part way through.
+func (p *Processor) resumeCollection(ctx context.Context, dbw *db.DbWriter, params search.SearchParams, status *db.CollectionStatusDto) error {
+	collectionId := status.CollectionId
+	if status.IsComplete {
+		fmt.Printf("Collection %d is already complete, skipping.\n", collectionId)
+		return nil
+	}
+	if status.SeededPages >= status.TotalPages {
+		return nil
+	}
+	numbers, err := dbw.GetSeededPageNumbers(ctx, collectionId)
+	if err != nil {
+		return err
+	}
+	seeded := make(map[int]bool, len(numbers))
+	for _, n := range numbers {
+		seeded[n] = true
+	}
+	// pages already seeded were laid out with the collection's page size,
+	// which may differ from the one configured now
+	if source, err := search.ParseSearchURL(status.SourceUrl); err == nil && source.Limit > 0 {
+		params.Limit = source.Limit
+	}
+	fmt.Printf("Resuming collection %d with %d of %d pages seeded\n", collectionId, len(seeded), status.TotalPages)
+	return p.seedPages(ctx, dbw, params, collectionId, 1, status.TotalPages, seeded)
+}
+
+func pageCount(totalRecords int, limit int) int {
+	return (totalRecords + limit - 1) / limit
+}
+
+// seedPages inserts a pending page for each page number from first to last,
+// skipping those in seeded.
+func (p *Processor) seedPages(ctx context.Context, dbw *db.DbWriter, params search.SearchParams, collectionId int, first int, last int, seeded map[int]bool) error {
+	pageBatch := p.config.ProcessorConfig.BatchSize
 	batch := make([]db.PageDto, 0, pageBatch)
 	inserted := 0
-	for i := 0; i < totalRecords; i += searchParams.Limit {
+	for pageNumber := first; pageNumber <= last; pageNumber++ {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("operation cancelled: %w", ctx.Err())
 		default:
 		}
-		pageNumber := (i / searchParams.Limit) + 1
+		if seeded[pageNumber] {
+			continue
+		}
 		params.Page = pageNumber
-		params.Skip = i
+		params.Skip = (pageNumber - 1) * params.Limit
 		newUrl := p.buildSearchURL(params)
 		page := &db.PageDto{
 			CollectionId:  collectionId,
@@ -126,101 +365,176 @@ func (p *Processor) CollectionStart(ctx context.Context, dbw *db.DbWriter, searc
 }
 
 func (p *Processor) buildSearchURL(params search.SearchParams) string {
-	u, err := url.Parse(p.baseURL)
+	u, err := params.BuildURL(p.baseURL)
 	if err != nil {
 		return p.baseURL
 	}
-	q := u.Query()
-	q.Set("ajax", "true")
-	if params.DeathYear > 0 {
-		q.Set("deathyear", strconv.Itoa(params.DeathYear))
-	}
-	q.Set("page", strconv.Itoa(params.Page))
-	q.Set("limit", strconv.Itoa(params.Limit))
-	q.Set("skip", strconv.Itoa(params.Skip))
-	if params.LName != nil {
-		q.Set("lastName", *params.LName)
+	return u
+}
+
+// makeRequestWithRetry returns the first successful response for url, read
+// in full. See openWithRetry.
+func (p *Processor) makeRequestWithRetry(ctx context.Context, url string) (*client.Response, error) {
+	response, body, err := p.openWithRetry(ctx, url)
+	if err != nil {
+		return nil, err
 	}
-	if params.FName != nil {
-		q.Set("firstName", *params.FName)
+	defer body.Close()
+	if response.Body, err = io.ReadAll(body); err != nil {
+		return nil, readFailure(response, err)
 	}
-	encoded := q.Encode()
-	wilcard := strings.ReplaceAll(encoded, "%2A", "*")
-	u.RawQuery = wilcard
-	return u.String()
+	return response, nil
 }
 
-func (p *Processor) makeRequestWithRetry(ctx context.Context, url string) (*client.Response, error) {
-	var lasterr error
-	client := client.NewPageClient(p.httpConfig)
-	for attempt := 0; attempt <= p.retryAttmpts; attempt++ {
-		if attempt > 0 {
-			fmt.Printf("Retrying request to %s (attempt %d)\n", url, attempt)
-
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(p.retryDelay):
+// openWithRetry returns the first successful response for url with its body
+// unread, for the caller to decode and close. When the retry policy gives
+// up, the error is a *RequestError describing the last attempt. Refusals are
+// returned at once, since retrying them only makes things worse.
+func (p *Processor) openWithRetry(ctx context.Context, url string) (*client.Response, io.ReadCloser, error) {
+	for attempt := 1; ; attempt++ {
+		response, body, err := p.client.Stream(ctx, url)
+		if err == nil {
+			if response.StatusCode >= 200 && response.StatusCode < 300 {
+				return response, body, nil
 			}
+			// only enough of an error response is kept to report it
+			response.Body, _ = io.ReadAll(io.LimitReader(body, 4096))
+			body.Close()
 		}
-		response, err := client.Get(ctx, url)
-		if err != nil {
-			lasterr = err
-			continue
+		failure := ClassifyResponse(response, err)
+		failure.Attempts = attempt
+		if response != nil {
+			failure.Latency = response.Duration
+		}
+		if failure.Outcome.Refusal() {
+			return nil, nil, failure
 		}
 
-		if response.StatusCode >= 200 && response.StatusCode < 300 {
-			return response, nil
+		delay, retry := p.retryPolicy.Next(attempt, failure)
+		if !retry || ctx.Err() != nil {
+			return nil, nil, failure
+		}
+		fmt.Printf("Request to %s failed (%v), retrying in %v (attempt %d)\n", url, failure.Err, delay, attempt+1)
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(delay):
 		}
-		lasterr = fmt.Errorf("HTTP %d: %s", response.StatusCode, string(response.Body))
 	}
+}
 
-	return nil, fmt.Errorf("failed to get response after %d attempts: %w", p.retryAttmpts+1, lasterr)
+// reconcileDrift compares the total a page reported with the one its
+// collection was seeded from. Pages are appended when the result set has
+// grown, and the collection is flagged when it has moved by more than the
+// configured tolerance.
+func (pp *Processor) reconcileDrift(ctx context.Context, dbw *db.DbWriter, page *db.Page, total int) error {
+	if !page.ExpectedRecords.Valid || int(page.ExpectedRecords.Int32) == total {
+		return nil
+	}
+	drift, err := dbw.RecordCollectionDrift(ctx, page.CollectionId, total, pp.config.ProcessorConfig.DriftTolerance)
+	if err != nil {
+		return err
+	}
+	if !drift.Updated {
+		return nil
+	}
+	fmt.Printf("Collection %d search total moved from %d to %d records\n", page.CollectionId, page.ExpectedRecords.Int32, total)
+	if drift.CoverageSuspect {
+		fmt.Printf("Collection %d coverage is suspect and it will be re-crawled on the next seed\n", page.CollectionId)
+	}
+	if drift.TotalPages <= drift.PreviousPages {
+		return nil
+	}
+	params, err := search.ParseSearchURL(page.SearchUrl)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Collection %d grew from %d to %d pages\n", page.CollectionId, drift.PreviousPages, drift.TotalPages)
+	return pp.seedPages(ctx, dbw, params, page.CollectionId, drift.PreviousPages+1, drift.TotalPages, nil)
+}
+
+// isSorted reports whether the search at url asks for a sort order, which
+// incremental collections only do when sorting newest first.
+func isSorted(url string) bool {
+	params, err := search.ParseSearchURL(url)
+	return err == nil && params.OrderBy != ""
 }
 
-func (pp *Processor) ProcessSingleSearch(ctx context.Context, page *db.Page) error {
+func (pp *Processor) ProcessSingleSearch(ctx context.Context, dbw *db.DbWriter, page *db.Page) (PageResult, error) {
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("operation cancelled: %w", ctx.Err())
+		return PageResult{}, fmt.Errorf("operation cancelled: %w", ctx.Err())
 	default:
 	}
 
 	searchUrl := page.SearchUrl
 
-	response, err := pp.makeRequestWithRetry(ctx, searchUrl)
+	response, body, err := pp.openWithRetry(ctx, searchUrl)
 	if err != nil {
 		fmt.Println(fmt.Errorf("failed to get search page for direct URL: %w", err))
-		return err
+		return PageResult{}, err
 	}
+	defer body.Close()
 
-	var searchresp search.SearchResponse
-	if err := json.Unmarshal(response.Body, &searchresp); err != nil {
-		fmt.Println(fmt.Errorf("failed to unmarshal search response: %w\nResponse Body: %s", err, string(response.Body)))
-		return err
+	// the client's archive capture, if configured, reports where the
+	// response was kept
+	pageResult := PageResult{
+		ArchiveKey: response.Headers.Get(archive.KeyHeader),
+		StatusCode: response.StatusCode,
+		Latency:    response.Duration,
+	}
+	var records []search.Memorial
+	incremental := page.Mode == db.CollectionModeIncremental
+	changes := search.ChangeFilter{Since: page.Since.Time}
+	searchresp, err := DecodeSearchStream(response, body, func(m search.Memorial) error {
+		pageResult.Records++
+		if !incremental || changes.Keep(m) {
+			records = append(records, m)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println(fmt.Errorf("failed to read search response for page %d: %w", page.PageNumber, err))
+		pageResult.Records = 0
+		return pageResult, err
+	}
+
+	if incremental {
+		// Incremental collections expect the total to grow as memorials are
+		// added, so drift is not reconciled.
+		pageResult.Newest = changes.Newest
+		pageResult.Exhausted = len(records) == 0 && isSorted(searchUrl)
+		fmt.Printf("Page %d has %d of %d records changed since %v\n", page.PageNumber, len(records), pageResult.Records, page.Since.Time)
+	} else if err := pp.reconcileDrift(ctx, dbw, page, searchresp.Total); err != nil {
+		fmt.Println(fmt.Errorf("failed to reconcile drift for collection %d: %w", page.CollectionId, err))
 	}
 
 	resultChan := make(chan MemorialBatchResult)
 	batch := MemorialBatch{
 		CollectionId: page.CollectionId,
 		Page:         *page,
-		Memorials:    searchresp.Records,
+		Memorials:    records,
 		SearchURL:    searchUrl,
 		ResultChan:   resultChan,
+		Upsert:       incremental,
+	}
+	fmt.Printf("Received response for page %d with %d records\n", page.PageNumber, pageResult.Records)
+	if len(records) == 0 {
+		return pageResult, nil
 	}
-	fmt.Printf("Received response for page %d with %d records\n", page.PageNumber, len(searchresp.Records))
 	err = pp.memproc.ProcessMemorials(ctx, batch)
 	if err != nil {
 		fmt.Println(fmt.Errorf("failed to process memorials: %w", err))
-		return err
+		return pageResult, err
 	}
 	result := <-resultChan
 	if result.Error != nil {
-		return fmt.Errorf("failed to process memorials: %w", result.Error)
+		return pageResult, fmt.Errorf("failed to process memorials: %w", result.Error)
 	}
 	ids := make([]int64, 0, len(result.Batch.Memorials))
 	for _, record := range result.Batch.Memorials {
 		ids = append(ids, record.MemorialID)
 	}
 	pp.memproc.UpdateSeenCache(ids)
-	return nil
+	return pageResult, nil
 }
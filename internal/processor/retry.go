@@ -0,0 +1,188 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/ChaseHampton/gofindag/internal/config"
+)
+
+// FailureClass says whether a failed request is worth trying again.
+type FailureClass string
+
+const (
+	FailureRetryable FailureClass = "retryable"
+	FailurePermanent FailureClass = "permanent"
+)
+
+// RequestError is a failed page request and how it was classified.
+type RequestError struct {
+	Class      FailureClass
+	StatusCode int
+	// RetryAfter is how long the server asked us to wait, if it did.
+	RetryAfter time.Duration
+	Attempts   int
+	Err        error
+	// Outcome is what the response contained, if one was received, and
+	// Latency how long it took to arrive.
+	Outcome Outcome
+	Latency time.Duration
+}
+
+func (e *RequestError) Error() string {
+	if e.Attempts > 0 {
+		return fmt.Sprintf("%s failure after %d attempts: %v", e.Class, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("%s failure: %v", e.Class, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyResponse returns nil for a successful response, and otherwise
+// whether the failure is retryable: transport errors, timeouts, 429 and 5xx
+// are, other 4xx responses and cancellation are not. A 403 is a refusal.
+func ClassifyResponse(resp *client.Response, err error) *RequestError {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return &RequestError{Class: FailurePermanent, Err: err}
+		}
+		if errors.Is(err, client.ErrBodyTooLarge) {
+			return &RequestError{Class: FailurePermanent, Outcome: OutcomeTooLarge, Err: err}
+		}
+		return &RequestError{Class: FailureRetryable, Err: err}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	failure := &RequestError{
+		Class:      FailurePermanent,
+		StatusCode: resp.StatusCode,
+		Err:        fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(string(resp.Body), 200)),
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		failure.Class = FailureRetryable
+		failure.RetryAfter = parseRetryAfter(resp.Headers.Get("Retry-After"), time.Now())
+	case resp.StatusCode == http.StatusForbidden:
+		failure.Class = FailureRetryable
+		failure.Outcome = OutcomeRefused
+	}
+	return failure
+}
+
+// FailureOf returns the class and a short reason for a page that failed
+// with err. Errors that were never classified are treated as retryable.
+func FailureOf(err error) (FailureClass, string) {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.Class, truncate(reqErr.Error(), 1000)
+	}
+	return FailureRetryable, truncate(err.Error(), 1000)
+}
+
+// FailureCause names what went wrong with err in a word or two, such as
+// http_503, html or timeout, so dead pages can be grouped by cause.
+func FailureCause(err error) string {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		switch {
+		case reqErr.Outcome != "" && reqErr.Outcome != OutcomeOK:
+			return string(reqErr.Outcome)
+		case reqErr.StatusCode != 0:
+			return fmt.Sprintf("http_%d", reqErr.StatusCode)
+		}
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case reqErr != nil:
+		return "transport"
+	}
+	return "error"
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// RetryPolicy decides whether a failed request is tried again and how long
+// to wait first.
+type RetryPolicy interface {
+	// Next is called after the attempt-th failed attempt.
+	Next(attempt int, failure *RequestError) (time.Duration, bool)
+}
+
+// ExponentialBackoff doubles the delay after every failed attempt, up to Max,
+// and randomly shortens each delay by up to Jitter of itself so workers do not
+// retry in lockstep. A Retry-After from the server is waited out in full
+// unless it is longer than MaxRetryAfter, in which case the request is given
+// up on.
+type ExponentialBackoff struct {
+	MaxAttempts   int
+	Base          time.Duration
+	Max           time.Duration
+	MaxRetryAfter time.Duration
+	Jitter        float64
+}
+
+func NewExponentialBackoff(cfg config.ProcessorConfig) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts:   cfg.RetryAttempts + 1,
+		Base:          cfg.RetryDelay,
+		Max:           cfg.RetryMaxDelay,
+		MaxRetryAfter: cfg.RetryAfterMax,
+		Jitter:        0.5,
+	}
+}
+
+func (b *ExponentialBackoff) Next(attempt int, failure *RequestError) (time.Duration, bool) {
+	if failure.Class == FailurePermanent || attempt >= b.MaxAttempts {
+		return 0, false
+	}
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	delay -= time.Duration(rand.Float64() * b.Jitter * float64(delay))
+
+	if failure.RetryAfter > 0 {
+		if failure.RetryAfter > b.MaxRetryAfter {
+			return 0, false
+		}
+		if failure.RetryAfter > delay {
+			delay = failure.RetryAfter
+		}
+	}
+	return delay, true
+}
@@ -0,0 +1,138 @@
+package processor_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/client"
+	"github.com/ChaseHampton/gofindag/internal/db"
+	"github.com/ChaseHampton/gofindag/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func response(status int, headers map[string]string) *client.Response {
+	h := http.Header{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return &client.Response{StatusCode: status, Headers: h}
+}
+
+func TestClassifyResponse(t *testing.T) {
+	cases := map[string]struct {
+		resp  *client.Response
+		err   error
+		class processor.FailureClass
+	}{
+		"not found":   {resp: response(404, nil), class: processor.FailurePermanent},
+		"bad request": {resp: response(400, nil), class: processor.FailurePermanent},
+		"forbidden":   {resp: response(403, nil), class: processor.FailureRetryable},
+		"rate limit":  {resp: response(429, nil), class: processor.FailureRetryable},
+		"server":      {resp: response(502, nil), class: processor.FailureRetryable},
+		"timeout":     {err: context.DeadlineExceeded, class: processor.FailureRetryable},
+		"cancelled":   {err: fmt.Errorf("get: %w", context.Canceled), class: processor.FailurePermanent},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			failure := processor.ClassifyResponse(tc.resp, tc.err)
+
+			require.NotNil(t, failure)
+			assert.Equal(t, tc.class, failure.Class)
+		})
+	}
+	assert.Nil(t, processor.ClassifyResponse(response(200, nil), nil))
+}
+
+func TestClassifyResponse_RetryAfter(t *testing.T) {
+	failure := processor.ClassifyResponse(response(503, map[string]string{"Retry-After": "7"}), nil)
+	assert.Equal(t, 7*time.Second, failure.RetryAfter)
+
+	at := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	failure = processor.ClassifyResponse(response(429, map[string]string{"Retry-After": at}), nil)
+	assert.InDelta(t, 90*time.Second, failure.RetryAfter, float64(2*time.Second))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := &processor.ExponentialBackoff{MaxAttempts: 5, Base: time.Second, Max: 5 * time.Second, MaxRetryAfter: time.Minute}
+	retryable := &processor.RequestError{Class: processor.FailureRetryable}
+
+	var delays []time.Duration
+	for attempt := 1; attempt < 5; attempt++ {
+		delay, ok := policy.Next(attempt, retryable)
+		require.True(t, ok)
+		delays = append(delays, delay)
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, delays)
+
+	_, ok := policy.Next(5, retryable)
+	assert.False(t, ok, "attempts are exhausted")
+
+	_, ok = policy.Next(1, &processor.RequestError{Class: processor.FailurePermanent})
+	assert.False(t, ok, "permanent failures are not retried")
+}
+
+func TestExponentialBackoff_RetryAfterAndJitter(t *testing.T) {
+	policy := &processor.ExponentialBackoff{MaxAttempts: 5, Base: time.Second, Max: 5 * time.Second, MaxRetryAfter: time.Minute, Jitter: 0.5}
+
+	delay, ok := policy.Next(1, &processor.RequestError{Class: processor.FailureRetryable, RetryAfter: 30 * time.Second})
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, delay)
+
+	_, ok = policy.Next(1, &processor.RequestError{Class: processor.FailureRetryable, RetryAfter: time.Hour})
+	assert.False(t, ok, "waits longer than MaxRetryAfter are given up on")
+
+	for range 20 {
+		delay, _ := policy.Next(2, &processor.RequestError{Class: processor.FailureRetryable})
+		assert.GreaterOrEqual(t, delay, time.Second)
+		assert.LessOrEqual(t, delay, 2*time.Second)
+	}
+}
+
+func TestFailureOf(t *testing.T) {
+	class, reason := processor.FailureOf(&processor.RequestError{Class: processor.FailurePermanent, Attempts: 1, Err: errors.New("HTTP 404: ")})
+	assert.Equal(t, processor.FailurePermanent, class)
+	assert.Contains(t, reason, "HTTP 404")
+
+	class, _ = processor.FailureOf(errors.New("boom"))
+	assert.Equal(t, processor.FailureRetryable, class)
+}
+
+func TestFailureCause(t *testing.T) {
+	cases := map[string]struct {
+		err   error
+		cause string
+	}{
+		"status":    {err: processor.ClassifyResponse(response(503, nil), nil), cause: "http_503"},
+		"refused":   {err: processor.ClassifyResponse(response(403, nil), nil), cause: "refused"},
+		"too large": {err: processor.ClassifyResponse(nil, client.ErrBodyTooLarge), cause: "too_large"},
+		"timeout":   {err: processor.ClassifyResponse(nil, context.DeadlineExceeded), cause: "timeout"},
+		"transport": {err: processor.ClassifyResponse(nil, errors.New("connection reset")), cause: "transport"},
+		"other":     {err: errors.New("boom"), cause: "error"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.cause, processor.FailureCause(tc.err))
+		})
+	}
+}
+
+func TestGetPageUpdate_ReportsLastResponse(t *testing.T) {
+	page := &db.Page{PageId: 7}
+
+	update := processor.GetPageUpdate(page, processor.PageCompleted, processor.PageResult{Records: 20, StatusCode: 200, Latency: time.Second}, nil)
+	assert.Equal(t, 200, update.StatusCode)
+	assert.Equal(t, time.Second, update.Latency)
+
+	failure := processor.ClassifyResponse(response(503, nil), nil)
+	failure.Latency = 2 * time.Second
+	update = processor.GetPageUpdate(page, processor.PageFailed, processor.PageResult{}, failure)
+	assert.Equal(t, 503, update.StatusCode)
+	assert.Equal(t, 2*time.Second, update.Latency)
+}
@@ -1,6 +1,10 @@
 package processor
 
 import (
+	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/ChaseHampton/gofindag/internal/db"
 	"github.com/ChaseHampton/gofindag/internal/search"
 )
@@ -11,6 +15,9 @@ type MemorialBatch struct {
 	CollectionId int
 	Page         db.Page
 	ResultChan   chan<- MemorialBatchResult
+	// Upsert writes every memorial in the batch, including ones already
+	// seen, so incremental crawls pick up changes to known memorials.
+	Upsert bool
 }
 
 type MemorialBatchResult struct {
@@ -18,10 +25,53 @@ type MemorialBatchResult struct {
 	Batch *MemorialBatch
 }
 
+// SeedOptions carries the context a search is seeded under to every
+// collection it produces.
+type SeedOptions struct {
+	// PlanId is the crawl plan that produced the search, 0 if none.
+	PlanId int
+	// Refresh seeds a new collection for searches that have already been
+	// completed instead of skipping them.
+	Refresh bool
+	// Incremental seeds collections that only gather records changed since
+	// the search's watermark. Completed searches are always re-seeded.
+	Incremental bool
+	// Budget limits each collection; unset limits fall back to the
+	// configured defaults.
+	Budget Budget
+	// Priority is given to each new collection, see db.SchedulePriority.
+	Priority int
+}
+
+// PageResult describes what a single page request returned.
+type PageResult struct {
+	Records int
+	// Newest is the most recent change time among the page's records.
+	Newest time.Time
+	// Exhausted is set when an incremental page had nothing newer than the
+	// watermark and the search is sorted, so later pages need not be fetched.
+	Exhausted bool
+	// ArchiveKey is where the raw response was archived, empty if it was not.
+	ArchiveKey string
+	// StatusCode and Latency describe the response the page was read from.
+	StatusCode int
+	Latency    time.Duration
+}
+
 type PageUpdate struct {
-	PageId int
-	Status PageStatus
-	Error  error
+	PageId    int
+	Status    PageStatus
+	Records   int
+	Newest    time.Time
+	Exhausted bool
+	Error     error
+	// ArchiveKey is where the page's raw response was archived, if it was.
+	ArchiveKey string
+	// StartedAt is when the attempt began, and StatusCode and Latency
+	// describe its last response, if one was received.
+	StartedAt  time.Time
+	StatusCode int
+	Latency    time.Duration
 }
 
 type PageStatus int
@@ -31,10 +81,55 @@ const (
 	PageFailed
 )
 
-func GetPageUpdate(page *db.Page, status PageStatus, err error) PageUpdate {
-	return PageUpdate{
-		PageId: page.PageId,
-		Status: status,
-		Error:  err,
+func GetPageUpdate(page *db.Page, status PageStatus, result PageResult, err error) PageUpdate {
+	update := PageUpdate{
+		PageId:    page.PageId,
+		Status:    status,
+		Records:   result.Records,
+		Newest:    result.Newest,
+		Exhausted: result.Exhausted,
+		Error:     err,
+
+		ArchiveKey: result.ArchiveKey,
+		StatusCode: result.StatusCode,
+		Latency:    result.Latency,
+	}
+	// a request that failed outright still reports its last response
+	var reqErr *RequestError
+	if update.StatusCode == 0 && errors.As(err, &reqErr) {
+		update.StatusCode = reqErr.StatusCode
+		update.Latency = reqErr.Latency
 	}
+	return update
+}
+
+func (pu PageUpdate) collected() db.PageCollectedDto {
+	return db.PageCollectedDto{
+		PageId:       pu.PageId,
+		RecordCount:  pu.Records,
+		NewestRecord: sql.NullTime{Time: pu.Newest, Valid: !pu.Newest.IsZero()},
+		Exhausted:    pu.Exhausted,
+		ArchiveKey:   pu.archiveKey(),
+		Attempt:      pu.attempt(),
+	}
+}
+
+func (pu PageUpdate) failed() db.PageFailedDto {
+	class, reason := FailureOf(pu.Error)
+	return db.PageFailedDto{
+		PageId:       pu.PageId,
+		FailureClass: string(class),
+		FailureCause: FailureCause(pu.Error),
+		Reason:       reason,
+		ArchiveKey:   pu.archiveKey(),
+		Attempt:      pu.attempt(),
+	}
+}
+
+func (pu PageUpdate) attempt() db.AttemptDto {
+	return db.AttemptDto{StartedAt: pu.StartedAt, StatusCode: pu.StatusCode, Latency: pu.Latency}
+}
+
+func (pu PageUpdate) archiveKey() sql.NullString {
+	return sql.NullString{String: pu.ArchiveKey, Valid: pu.ArchiveKey != ""}
 }
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"strings"
+	"time"
+)
+
+// changeLayouts are the timestamp formats seen in the memorial date fields.
+// Timestamps without a zone are taken to be UTC.
+var changeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ChangedAt returns the most recent of the memorial's modified, indexed and
+// created timestamps. ok is false if none of them could be parsed.
+func (m Memorial) ChangedAt() (changed time.Time, ok bool) {
+	for _, raw := range []string{m.DateModified, m.IndexTimestamp, m.IntermentDateCreated} {
+		t, parsed := parseChangeTime(raw)
+		if parsed && t.After(changed) {
+			changed, ok = t, true
+		}
+	}
+	return changed, ok
+}
+
+func parseChangeTime(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range changeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ChangeFilter keeps the records that changed after Since, one record at a
+// time as they are decoded. Records whose timestamps cannot be parsed are
+// kept, so an unfamiliar format never drops data.
+type ChangeFilter struct {
+	Since time.Time
+	// Newest is the most recent change time among the records seen so far.
+	Newest time.Time
+}
+
+// Keep reports whether record changed after Since.
+func (f *ChangeFilter) Keep(record Memorial) bool {
+	t, ok := record.ChangedAt()
+	if !ok {
+		return true
+	}
+	if t.After(f.Newest) {
+		f.Newest = t
+	}
+	return t.After(f.Since)
+}
@@ -0,0 +1,34 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ChaseHampton/gofindag/internal/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorial_ChangedAt(t *testing.T) {
+	m := search.Memorial{
+		DateModified:         "2024-03-01T10:00:00Z",
+		IndexTimestamp:       "2024-03-02 08:30:00",
+		IntermentDateCreated: "2019-07-04",
+	}
+
+	changed, ok := m.ChangedAt()
+
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC), changed)
+
+	_, ok = search.Memorial{DateModified: "yesterday"}.ChangedAt()
+	assert.False(t, ok)
+}
+
+func TestChangeFilter(t *testing.T) {
+	filter := search.ChangeFilter{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	assert.True(t, filter.Keep(search.Memorial{MemorialID: 1, DateModified: "2024-02-01T00:00:00Z"}))
+	assert.False(t, filter.Keep(search.Memorial{MemorialID: 2, DateModified: "2023-06-01T00:00:00Z"}))
+	assert.True(t, filter.Keep(search.Memorial{MemorialID: 3, DateModified: "not a date"}))
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), filter.Newest)
+}
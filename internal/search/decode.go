@@ -0,0 +1,122 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrMalformed is returned by DecodeResponse for input that is not a search
+// response object.
+var ErrMalformed = errors.New("malformed search response")
+
+// DecodeResponse reads a search response from r, passing each memorial in
+// its records to fn as soon as it is decoded instead of unmarshalling the
+// whole response at once. The collection array, which repeats the records,
+// is skipped, so the returned response has neither Records nor Collection.
+// An error from fn stops decoding and is returned as is.
+func DecodeResponse(r io.Reader, fn func(Memorial) error) (*SearchResponse, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	// the remaining fields are small, so they are gathered and unmarshalled
+	// into the response in one go
+	fields := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected %v", ErrMalformed, tok)
+		}
+		switch {
+		case strings.EqualFold(key, "records"):
+			if err := decodeRecords(dec, fn); err != nil {
+				return nil, err
+			}
+		case strings.EqualFold(key, "collection"):
+			if err := skipValue(dec); err != nil {
+				return nil, err
+			}
+		default:
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			fields[key] = raw
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var resp SearchResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func decodeRecords(dec *json.Decoder, fn func(Memorial) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("%w: records are %v, not an array", ErrMalformed, tok)
+	}
+	for dec.More() {
+		var m Memorial
+		if err := dec.Decode(&m); err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// skipValue consumes the next value without keeping it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("%w: expected %v, got %v", ErrMalformed, want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package search_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const streamedResponse = `{
+	"total": 3,
+	"collection": [{"memorialId": 1, "nested": {"a": [1, 2]}}, {"memorialId": 2}],
+	"records": [{"memorialId": 1}, {"memorialId": 2}, {"memorialId": 3}],
+	"page": 1,
+	"responseCode": 200,
+	"ancestryLogin": false
+}`
+
+func TestDecodeResponse_StreamsRecords(t *testing.T) {
+	var ids []int64
+	resp, err := search.DecodeResponse(strings.NewReader(streamedResponse), func(m search.Memorial) error {
+		ids = append(ids, m.MemorialID)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, 1, resp.Page)
+	assert.Equal(t, 200, resp.ResponseCode)
+	assert.Empty(t, resp.Records)
+	assert.Empty(t, resp.Collection)
+}
+
+func TestDecodeResponse_StopsOnCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	_, err := search.DecodeResponse(strings.NewReader(streamedResponse), func(search.Memorial) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDecodeResponse_Malformed(t *testing.T) {
+	noop := func(search.Memorial) error { return nil }
+	for _, body := range []string{`[]`, `{"records": {}}`, `{"total": 1`, ``} {
+		_, err := search.DecodeResponse(strings.NewReader(body), noop)
+		assert.Error(t, err, body)
+	}
+	_, err := search.DecodeResponse(strings.NewReader(`{"records": null}`), noop)
+	assert.NoError(t, err)
+}
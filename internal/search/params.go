@@ -0,0 +1,293 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// YearFilter narrows how a birth or death year is matched. Numeric filters
+// match the year plus or minus that many years.
+type YearFilter string
+
+const (
+	YearExact    YearFilter = ""
+	YearBefore   YearFilter = "before"
+	YearAfter    YearFilter = "after"
+	YearWithin1  YearFilter = "1"
+	YearWithin3  YearFilter = "3"
+	YearWithin5  YearFilter = "5"
+	YearWithin10 YearFilter = "10"
+	YearWithin25 YearFilter = "25"
+)
+
+// PhotoFilter restricts results by whether the memorial has photos.
+type PhotoFilter string
+
+const (
+	PhotosAny  PhotoFilter = ""
+	PhotosOnly PhotoFilter = "photos"
+	PhotosNone PhotoFilter = "nophotos"
+)
+
+var (
+	locationIdPattern   = regexp.MustCompile(`^(country|state|county|city)_[0-9]+$`)
+	cemeteryPathPattern = regexp.MustCompile(`^/cemetery/([0-9]+)/memorial-search$`)
+)
+
+// CemeterySearchPath is the path of the memorial search scoped to a single
+// cemetery.
+func CemeterySearchPath(cemeteryId int) string {
+	return fmt.Sprintf("/cemetery/%d/memorial-search", cemeteryId)
+}
+
+// Values encodes the search filters as query parameters. CemeteryId is not
+// included because it is part of the path, see CemeterySearchPath.
+func (sp SearchParams) Values() url.Values {
+	q := url.Values{}
+	q.Set("ajax", "true")
+	q.Set("page", strconv.Itoa(sp.Page))
+	q.Set("limit", strconv.Itoa(sp.Limit))
+	q.Set("skip", strconv.Itoa(sp.Skip))
+	if sp.DeathYear > 0 {
+		q.Set("deathyear", strconv.Itoa(sp.DeathYear))
+	}
+	if sp.DeathYearFilter != YearExact {
+		q.Set("deathyearfilter", string(sp.DeathYearFilter))
+	}
+	if sp.BirthYear > 0 {
+		q.Set("birthyear", strconv.Itoa(sp.BirthYear))
+	}
+	if sp.BirthYearFilter != YearExact {
+		q.Set("birthyearfilter", string(sp.BirthYearFilter))
+	}
+	setOptional(q, "firstName", sp.FName)
+	setOptional(q, "middleName", sp.MName)
+	setOptional(q, "lastName", sp.LName)
+	setOptional(q, "maidenName", sp.MaidenName)
+	setOptional(q, "location", sp.Location)
+	setOptional(q, "locationId", sp.LocationId)
+	if sp.IsVeteran {
+		q.Set("isVeteran", "true")
+	}
+	if sp.IsFamous {
+		q.Set("famous", "true")
+	}
+	if sp.PhotoFilter != PhotosAny {
+		q.Set("photofilter", string(sp.PhotoFilter))
+	}
+	if sp.HasPlot {
+		q.Set("hasPlot", "true")
+	}
+	if sp.OrderBy != "" {
+		q.Set("orderby", sp.OrderBy)
+	}
+	return q
+}
+
+// QueryKey identifies the result set sp describes regardless of which page
+// of it is requested or how many records a page holds, so the same search
+// can be recognised across runs. Names are compared case-insensitively, as
+// the search endpoint does.
+func (sp SearchParams) QueryKey() string {
+	q := sp.Values()
+	q.Del("page")
+	q.Del("skip")
+	q.Del("limit")
+	q.Del("ajax")
+	for _, key := range []string{"firstName", "middleName", "lastName", "maidenName", "location"} {
+		if q.Has(key) {
+			q.Set(key, strings.ToLower(q.Get(key)))
+		}
+	}
+	if sp.CemeteryId > 0 {
+		q.Set("cemeteryId", strconv.Itoa(sp.CemeteryId))
+	}
+	return q.Encode()
+}
+
+// Scope names what a search is confined to, e.g. "cemetery:42" for a
+// cemetery memorial search. It is empty for searches across the whole site.
+func (sp SearchParams) Scope() string {
+	if sp.CemeteryId > 0 {
+		return fmt.Sprintf("cemetery:%d", sp.CemeteryId)
+	}
+	return ""
+}
+
+// BuildURL encodes sp onto the search endpoint at base. Wildcards are left
+// unescaped because the endpoint does not expand %2A.
+func (sp SearchParams) BuildURL(base string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse base URL: %w", err)
+	}
+	if sp.CemeteryId > 0 {
+		u.Path = CemeterySearchPath(sp.CemeteryId)
+	}
+	q := u.Query()
+	for key, values := range sp.Values() {
+		q[key] = values
+	}
+	u.RawQuery = strings.ReplaceAll(q.Encode(), "%2A", "*")
+	return u.String(), nil
+}
+
+func setOptional(q url.Values, key string, value *string) {
+	if value != nil {
+		q.Set(key, *value)
+	}
+}
+
+// Set assigns a single filter by its query parameter name.
+func (sp *SearchParams) Set(key, value string) error {
+	var err error
+	switch key {
+	case "ajax":
+		sp.Ajax, err = strconv.ParseBool(value)
+	case "page":
+		sp.Page, err = strconv.Atoi(value)
+	case "limit":
+		sp.Limit, err = strconv.Atoi(value)
+	case "skip":
+		sp.Skip, err = strconv.Atoi(value)
+	case "deathyear":
+		sp.DeathYear, err = strconv.Atoi(value)
+	case "deathyearfilter":
+		sp.DeathYearFilter = YearFilter(value)
+	case "birthyear":
+		sp.BirthYear, err = strconv.Atoi(value)
+	case "birthyearfilter":
+		sp.BirthYearFilter = YearFilter(value)
+	case "firstName":
+		sp.FName = &value
+	case "middleName":
+		sp.MName = &value
+	case "lastName":
+		sp.LName = &value
+	case "maidenName":
+		sp.MaidenName = &value
+	case "location":
+		sp.Location = &value
+	case "locationId":
+		sp.LocationId = &value
+	case "cemeteryId":
+		sp.CemeteryId, err = strconv.Atoi(value)
+	case "isVeteran":
+		sp.IsVeteran, err = strconv.ParseBool(value)
+	case "famous":
+		sp.IsFamous, err = strconv.ParseBool(value)
+	case "photofilter":
+		sp.PhotoFilter = PhotoFilter(value)
+	case "hasPlot":
+		sp.HasPlot, err = strconv.ParseBool(value)
+	case "orderby":
+		sp.OrderBy = value
+	default:
+		return fmt.Errorf("unsupported search parameter %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for search parameter %s: %w", value, key, err)
+	}
+	return nil
+}
+
+// Validate reports the first filter that the search endpoint would reject.
+func (sp SearchParams) Validate() error {
+	if sp.Limit <= 0 {
+		return fmt.Errorf("limit must be positive, got %d", sp.Limit)
+	}
+	if sp.Page < 0 || sp.Skip < 0 {
+		return fmt.Errorf("page and skip must not be negative")
+	}
+	if err := validateYear("deathyear", sp.DeathYear, sp.DeathYearFilter); err != nil {
+		return err
+	}
+	if err := validateYear("birthyear", sp.BirthYear, sp.BirthYearFilter); err != nil {
+		return err
+	}
+	if err := validateName("firstName", sp.FName); err != nil {
+		return err
+	}
+	if err := validateName("middleName", sp.MName); err != nil {
+		return err
+	}
+	if err := validateName("lastName", sp.LName); err != nil {
+		return err
+	}
+	if err := validateName("maidenName", sp.MaidenName); err != nil {
+		return err
+	}
+	if sp.Location != nil && strings.TrimSpace(*sp.Location) == "" {
+		return fmt.Errorf("location must not be blank")
+	}
+	if sp.LocationId != nil && !locationIdPattern.MatchString(*sp.LocationId) {
+		return fmt.Errorf("locationId %q must look like country_4, state_19, county_123 or city_456", *sp.LocationId)
+	}
+	if sp.CemeteryId < 0 {
+		return fmt.Errorf("cemeteryId must not be negative, got %d", sp.CemeteryId)
+	}
+	switch sp.PhotoFilter {
+	case PhotosAny, PhotosOnly, PhotosNone:
+	default:
+		return fmt.Errorf("unsupported photofilter %q", sp.PhotoFilter)
+	}
+	return nil
+}
+
+func validateYear(key string, year int, filter YearFilter) error {
+	if year < 0 || year > 9999 {
+		return fmt.Errorf("%s %d is out of range", key, year)
+	}
+	switch filter {
+	case YearExact:
+		return nil
+	case YearBefore, YearAfter, YearWithin1, YearWithin3, YearWithin5, YearWithin10, YearWithin25:
+		if year == 0 {
+			return fmt.Errorf("%sfilter %q requires %s", key, filter, key)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported %sfilter %q", key, filter)
+	}
+}
+
+func validateName(key string, name *string) error {
+	if name == nil {
+		return nil
+	}
+	trimmed := strings.TrimSuffix(*name, "*")
+	if strings.TrimSpace(trimmed) == "" {
+		return fmt.Errorf("%s must contain at least one character before a wildcard", key)
+	}
+	if strings.Contains(trimmed, "*") {
+		return fmt.Errorf("%s %q may only use a trailing wildcard", key, *name)
+	}
+	return nil
+}
+
+// ParseSearchURL rebuilds the SearchParams a search URL was built from, such
+// as the SourceUrl stored on a collection.
+func ParseSearchURL(raw string) (SearchParams, error) {
+	var sp SearchParams
+	u, err := url.Parse(raw)
+	if err != nil {
+		return sp, fmt.Errorf("failed to parse search URL: %w", err)
+	}
+	if m := cemeteryPathPattern.FindStringSubmatch(u.Path); m != nil {
+		if err := sp.Set("cemeteryId", m[1]); err != nil {
+			return sp, err
+		}
+	}
+	for key, values := range u.Query() {
+		if len(values) != 1 {
+			return sp, fmt.Errorf("search parameter %s must appear once", key)
+		}
+		if err := sp.Set(key, values[0]); err != nil {
+			return sp, err
+		}
+	}
+	return sp, nil
+}
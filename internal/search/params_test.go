@@ -0,0 +1,123 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/ChaseHampton/gofindag/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseURL = "https://www.findagrave.com/memorial/search"
+
+func strptr(s string) *string {
+	return &s
+}
+
+func TestSearchParams_BuildURL_RoundTrip(t *testing.T) {
+	cases := map[string]search.SearchParams{
+		"name wildcards": {
+			Ajax: true, Page: 1, Limit: 20, DeathYear: 2025,
+			LName: strptr("A*"), FName: strptr("B*"),
+		},
+		"all filters": {
+			Ajax: true, Page: 3, Limit: 50, Skip: 100,
+			DeathYear: 1918, DeathYearFilter: search.YearWithin5,
+			BirthYear: 1890, BirthYearFilter: search.YearBefore,
+			FName: strptr("Mary"), MName: strptr("Ann"), LName: strptr("O'Brien"),
+			MaidenName: strptr("Smith*"),
+			Location:   strptr("Boston, Suffolk County, Massachusetts, USA"),
+			LocationId: strptr("city_12345"),
+			IsVeteran:  true, IsFamous: true, HasPlot: true,
+			PhotoFilter: search.PhotosNone,
+			OrderBy:     "d",
+		},
+		"cemetery": {
+			Ajax: true, Page: 1, Limit: 20, CemeteryId: 641348,
+			PhotoFilter: search.PhotosOnly,
+		},
+	}
+
+	for name, params := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, params.Validate())
+			built, err := params.BuildURL(baseURL)
+			require.NoError(t, err)
+
+			parsed, err := search.ParseSearchURL(built)
+			require.NoError(t, err)
+			assert.Equal(t, params, parsed)
+
+			rebuilt, err := parsed.BuildURL(baseURL)
+			require.NoError(t, err)
+			assert.Equal(t, built, rebuilt)
+		})
+	}
+}
+
+func TestSearchParams_BuildURL_KeepsWildcards(t *testing.T) {
+	params := search.SearchParams{Page: 1, Limit: 20, LName: strptr("SM*")}
+
+	built, err := params.BuildURL(baseURL)
+
+	require.NoError(t, err)
+	assert.Equal(t, baseURL+"?ajax=true&lastName=SM*&limit=20&page=1&skip=0", built)
+}
+
+func TestSearchParams_BuildURL_CemeteryPath(t *testing.T) {
+	params := search.SearchParams{Page: 1, Limit: 20, CemeteryId: 42}
+
+	built, err := params.BuildURL(baseURL)
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.findagrave.com/cemetery/42/memorial-search?ajax=true&limit=20&page=1&skip=0", built)
+}
+
+func TestSearchParams_Scope(t *testing.T) {
+	assert.Equal(t, "", search.SearchParams{Limit: 20}.Scope())
+	assert.Equal(t, "cemetery:42", search.SearchParams{Limit: 20, CemeteryId: 42}.Scope())
+}
+
+func TestSearchParams_QueryKey(t *testing.T) {
+	first := search.SearchParams{Page: 1, Limit: 20, Skip: 0, LName: strptr("Smith"), CemeteryId: 7}
+	later := search.SearchParams{Page: 4, Limit: 20, Skip: 60, LName: strptr("SMITH"), CemeteryId: 7}
+	otherLimit := first
+	otherLimit.Limit = 50
+	otherCemetery := first
+	otherCemetery.CemeteryId = 8
+
+	assert.Equal(t, first.QueryKey(), later.QueryKey(), "page position and name case should not matter")
+	assert.Equal(t, first.QueryKey(), otherLimit.QueryKey(), "page size should not matter")
+	assert.NotContains(t, first.QueryKey(), "ajax")
+	assert.NotEqual(t, first.QueryKey(), otherCemetery.QueryKey())
+}
+
+func TestSearchParams_Validate(t *testing.T) {
+	valid := search.SearchParams{Limit: 20}
+	cases := map[string]func(*search.SearchParams){
+		"zero limit":           func(sp *search.SearchParams) { sp.Limit = 0 },
+		"negative skip":        func(sp *search.SearchParams) { sp.Skip = -1 },
+		"filter without year":  func(sp *search.SearchParams) { sp.DeathYearFilter = search.YearAfter },
+		"unknown year filter":  func(sp *search.SearchParams) { sp.BirthYear, sp.BirthYearFilter = 1900, "2" },
+		"bare wildcard":        func(sp *search.SearchParams) { sp.LName = strptr("*") },
+		"inner wildcard":       func(sp *search.SearchParams) { sp.FName = strptr("J*n") },
+		"blank location":       func(sp *search.SearchParams) { sp.Location = strptr("  ") },
+		"malformed locationId": func(sp *search.SearchParams) { sp.LocationId = strptr("usa") },
+		"unknown photofilter":  func(sp *search.SearchParams) { sp.PhotoFilter = "some" },
+	}
+
+	assert.NoError(t, valid.Validate())
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			params := valid
+			mutate(&params)
+			assert.Error(t, params.Validate())
+		})
+	}
+}
+
+func TestParseSearchURL_RejectsUnknownParameters(t *testing.T) {
+	_, err := search.ParseSearchURL(baseURL + "?ajax=true&page=1&limit=20&skip=0&bogus=1")
+
+	assert.Error(t, err)
+}
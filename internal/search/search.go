@@ -1,13 +1,27 @@
 package search
 
 type SearchParams struct {
-	Ajax      bool
-	DeathYear int
-	Page      int
-	Limit     int
-	Skip      int
-	FName     *string
-	LName     *string
+	Ajax            bool
+	DeathYear       int
+	DeathYearFilter YearFilter
+	BirthYear       int
+	BirthYearFilter YearFilter
+	Page            int
+	Limit           int
+	Skip            int
+	FName           *string
+	MName           *string
+	LName           *string
+	MaidenName      *string
+	Location        *string
+	LocationId      *string
+	CemeteryId      int
+	IsVeteran       bool
+	IsFamous        bool
+	PhotoFilter     PhotoFilter
+	HasPlot         bool
+	// OrderBy is the site's sort order, passed through as-is.
+	OrderBy string
 }
 
 type SearchResponse struct {
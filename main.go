@@ -4,42 +4,130 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ChaseHampton/gofindag/internal/archive"
 	"github.com/ChaseHampton/gofindag/internal/client"
 	"github.com/ChaseHampton/gofindag/internal/config"
 	"github.com/ChaseHampton/gofindag/internal/db"
 	"github.com/ChaseHampton/gofindag/internal/duplicates"
 	"github.com/ChaseHampton/gofindag/internal/page"
+	"github.com/ChaseHampton/gofindag/internal/plan"
 	"github.com/ChaseHampton/gofindag/internal/processor"
-	"github.com/ChaseHampton/gofindag/internal/search"
 )
 
 func main() {
 	gen := os.Getenv("GEN")
+	planFile := os.Getenv("PLAN_FILE")
+	yearSweep := os.Getenv("YEAR_SWEEP")
+	cemeteryIds := os.Getenv("CEMETERY_IDS")
+	refresh := os.Getenv("REFRESH") != ""
+	incremental := os.Getenv("INCREMENTAL") != ""
+	requeueDead := os.Getenv("REQUEUE_DEAD")
+	controlAction := os.Getenv("CONTROL_ACTION")
 	starttime := time.Now()
 
 	dbcfg := config.NewDbConfig()
 
 	cfg := config.NewConfig()
-	p := &search.SearchParams{
-		Ajax:      true,
-		Limit:     cfg.ProcessorConfig.BatchSize,
-		Page:      1,
-		DeathYear: 2025,
-		Skip:      0,
+	var crawlPlan *plan.Plan
+	if planFile != "" {
+		loaded, err := plan.Load(planFile)
+		if err != nil {
+			fmt.Printf("failed to load crawl plan: %v", err)
+			return
+		}
+		crawlPlan = loaded
+	} else if yearSweep != "" {
+		sweep, err := plan.YearSweep(yearSweep,
+			config.LoadDefaultInt("YEAR_SWEEP_FROM", 0),
+			config.LoadDefaultInt("YEAR_SWEEP_TO", 0),
+			config.LoadDefaultInt("YEAR_SWEEP_LAST", 0),
+			config.LoadDefaultInt("YEAR_SWEEP_BUCKET", 1))
+		if err != nil {
+			fmt.Printf("invalid year sweep: %v", err)
+			return
+		}
+		crawlPlan = sweep
+	} else if cemeteryIds != "" {
+		cemeteries, err := plan.Cemeteries(cemeteryIds)
+		if err != nil {
+			fmt.Printf("invalid cemetery crawl: %v", err)
+			return
+		}
+		crawlPlan = cemeteries
+	} else if gen != "" {
+		crawlPlan = plan.Default()
+	}
+	if crawlPlan != nil && refresh {
+		crawlPlan.Refresh = true
+	}
+	if crawlPlan != nil && incremental {
+		crawlPlan.Incremental = true
+	}
+	if crawlPlan != nil {
+		crawlPlan.Priority = config.LoadDefaultInt("PRIORITY", crawlPlan.Priority)
+	}
+	var store *archive.Store
+	if cfg.ProcessorConfig.ArchiveDir != "" {
+		opened, err := archive.NewStore(cfg.ProcessorConfig.ArchiveDir)
+		if err != nil {
+			fmt.Printf("failed to open response archive: %v", err)
+			return
+		}
+		defer opened.Close()
+		store = opened
+	}
+	pageClient, err := newPageClient(cfg, store)
+	if err != nil {
+		fmt.Printf("failed to create page client: %v", err)
+		return
 	}
-	defaultClient := client.NewClient(&cfg.HTTPConfig)
+	defer pageClient.CloseIdleConnections()
+	defer func() {
+		if err := pageClient.SaveCookies(); err != nil {
+			fmt.Printf("failed to save cookies: %v\n", err)
+		}
+	}()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if cfg.HTTPConfig.SessionWarmup && !cfg.ProcessorConfig.Replay && controlAction == "" {
+		if err := pageClient.WarmUp(ctx, cfg.ProcessorConfig.BaseURL); err != nil {
+			fmt.Printf("Continuing without a warm session: %v\n", err)
+		}
+	}
+
 	dbw, err := db.NewDb(dbcfg, cfg)
 	if err != nil {
 		fmt.Printf("failed to connect to database: %v", err)
 		return
 	}
 
+	if controlAction == "priority" {
+		if err := setPriority(ctx, dbw, os.Getenv("CONTROL_COLLECTIONS"), os.Getenv("CONTROL_PLAN"), os.Getenv("CONTROL_PRIORITY")); err != nil {
+			fmt.Printf("Failed: %s", fmt.Errorf("failed to set priority: %v", err))
+		}
+		return
+	}
+
+	if controlAction != "" {
+		if err := controlCrawl(ctx, dbw, controlAction, os.Getenv("CONTROL_COLLECTIONS"), os.Getenv("CONTROL_REASON")); err != nil {
+			fmt.Printf("Failed: %s", fmt.Errorf("failed to %s crawl: %v", controlAction, err))
+		}
+		return
+	}
+
+	if requeueDead != "" {
+		if err := requeueDeadPages(ctx, dbw, requeueDead, os.Getenv("REQUEUE_DEAD_CAUSE")); err != nil {
+			fmt.Printf("Failed: %s", fmt.Errorf("failed to requeue dead pages: %v", err))
+			return
+		}
+	}
+
 	duper := duplicates.NewDuplicateProcessor(cfg, dbw)
 	duper.Start(ctx)
 	memwriter := processor.NewMemorialWriter(dbw, cfg)
@@ -47,21 +135,11 @@ func main() {
 	pageproc := processor.NewPageProcessor(dbw, cfg)
 	pageproc.Start(ctx)
 	memproc := processor.NewMemorialProcessor(ctx, dbw, memwriter, cfg, duper)
-	searchPro := processor.NewProcessor(defaultClient, cfg.ProcessorConfig, &cfg.HTTPConfig, cfg, memproc)
-	if gen != "" {
-		for c := 'A'; c <= 'Z'; c++ {
-			for c2 := 'A'; c2 <= 'Z'; c2++ {
-				lname := string(c) + "*"
-				fname := string(c2) + "*"
-				temp := *p
-				temp.LName = &lname
-				temp.FName = &fname
-				err = searchPro.CollectionStart(ctx, dbw, &temp)
-
-				if err != nil {
-					fmt.Printf("Failed: %s", fmt.Errorf("failed to start collection: %v", err))
-				}
-			}
+	searchPro := processor.NewProcessor(pageClient, cfg.ProcessorConfig, cfg, memproc)
+	if crawlPlan != nil {
+		if err := seedPlan(ctx, searchPro, dbw, crawlPlan, cfg.ProcessorConfig.BatchSize); err != nil {
+			fmt.Printf("Failed: %s", fmt.Errorf("failed to seed crawl plan: %v", err))
+			return
 		}
 	}
 	pager := page.NewPager(searchPro, dbw, pageproc, cfg)
@@ -72,5 +150,197 @@ func main() {
 	}
 	duper.Stop(ctx)
 	memwriter.Stop(ctx)
+	if metrics := pageClient.Metrics(); metrics != nil {
+		fmt.Printf("HTTP: %v\n", metrics.Snapshot())
+	}
 	fmt.Printf("Search completed successfully after %v\n", time.Since(starttime))
 }
+
+// newPageClient returns the client pages are fetched with. Responses are
+// captured in store when one is given, except in replay mode, where every
+// response comes from the archive and the network is never used.
+func newPageClient(cfg *config.Config, store *archive.Store) (*client.Client, error) {
+	if !cfg.ProcessorConfig.Replay {
+		limiters := client.NewHostLimiters(cfg.HTTPConfig.RateLimitRPS, cfg.HTTPConfig.RateLimitBurst)
+		if store == nil {
+			return client.NewPageClient(&cfg.HTTPConfig, limiters)
+		}
+		return client.NewPageClient(&cfg.HTTPConfig, limiters, archive.Capture(store))
+	}
+	if store == nil {
+		return nil, fmt.Errorf("REPLAY requires ARCHIVE_DIR")
+	}
+	return client.NewReplayClient(&cfg.HTTPConfig, store)
+}
+
+// requeueDeadPages reports why dead pages died and returns them to the queue.
+// collections is "all" or a comma separated list of collection IDs, and
+// cause, if set, limits the requeue to pages that died of it.
+func requeueDeadPages(ctx context.Context, dbw *db.DbWriter, collections string, cause string) error {
+	ids, err := collectionIds(collections)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		reasons, err := dbw.GetDeadPageReasons(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, r := range reasons {
+			fmt.Printf("Collection %d: %d dead pages (%s, %s): %s\n", r.CollectionId, r.Pages, r.DeadReason, r.FailureCause, r.SampleError)
+		}
+		requeued, err := dbw.RequeueDeadPages(ctx, id, cause)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Requeued %d dead pages\n", requeued)
+	}
+	return nil
+}
+
+// controlCrawl pauses, resumes or cancels the collections listed, or the
+// whole crawl when collections is "all", for every worker sharing the
+// database. Running workers pick the change up before fetching their next
+// page.
+func controlCrawl(ctx context.Context, dbw *db.DbWriter, action string, collections string, reason string) error {
+	switch action {
+	case db.ControlPause, db.ControlResume, db.ControlCancel:
+	default:
+		return fmt.Errorf("CONTROL_ACTION must be pause, resume, cancel or priority, not %q", action)
+	}
+	if collections == "" {
+		return fmt.Errorf("CONTROL_COLLECTIONS must be \"all\" or a list of collection IDs")
+	}
+	ids, err := collectionIds(collections)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		affected, err := dbw.ControlCrawl(ctx, action, id, reason)
+		if err != nil {
+			return err
+		}
+		if id == 0 && action == db.ControlCancel {
+			fmt.Printf("Cancelled %d collections\n", affected)
+		} else if id == 0 {
+			fmt.Printf("Crawl %s applied\n", action)
+		} else if affected == 0 {
+			fmt.Printf("Collection %d was not changed; it may be finished or already in that state\n", id)
+		} else {
+			fmt.Printf("Collection %d: %s applied\n", id, action)
+		}
+	}
+	return nil
+}
+
+// setPriority changes the priority of the collections listed, or of every
+// unfinished collection the plan with ID planId seeded, so workers reserve
+// their remaining pages sooner or later.
+func setPriority(ctx context.Context, dbw *db.DbWriter, collections string, planId string, priority string) error {
+	value, err := strconv.Atoi(priority)
+	if err != nil {
+		return fmt.Errorf("CONTROL_PRIORITY must be a number, not %q", priority)
+	}
+	if planId != "" {
+		id, err := strconv.Atoi(planId)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid plan ID %q", planId)
+		}
+		updated, err := dbw.SetCollectionPriority(ctx, value, 0, id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Plan %d: priority %d set on %d collections\n", id, value, updated)
+		return nil
+	}
+	if collections == "" || collections == "all" {
+		return fmt.Errorf("priority needs CONTROL_PLAN or a list of collection IDs in CONTROL_COLLECTIONS")
+	}
+	ids, err := collectionIds(collections)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		updated, err := dbw.SetCollectionPriority(ctx, value, id, 0)
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			fmt.Printf("Collection %d was not changed; it may be finished\n", id)
+		} else {
+			fmt.Printf("Collection %d: priority %d set\n", id, value)
+		}
+	}
+	return nil
+}
+
+// collectionIds parses a comma separated list of collection IDs. "all" is
+// returned as the single ID 0, which the database treats as every collection.
+func collectionIds(spec string) ([]int, error) {
+	if spec == "all" {
+		return []int{0}, nil
+	}
+	var ids []int
+	for _, field := range strings.Split(spec, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid collection ID %q", field)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// seedPlan expands the crawl plan into searches, starts a collection for each
+// one and reports the collections the plan produced.
+func seedPlan(ctx context.Context, searchPro *processor.Processor, dbw *db.DbWriter, crawlPlan *plan.Plan, defaultLimit int) error {
+	queries, err := crawlPlan.Expand(defaultLimit)
+	if err != nil {
+		return err
+	}
+	definition, err := crawlPlan.Definition()
+	if err != nil {
+		return err
+	}
+	planId, err := dbw.RegisterCrawlPlan(ctx, crawlPlan.Name, definition)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Seeding plan %q (ID %d) with %d searches\n", crawlPlan.Name, planId, len(queries))
+
+	deadline, err := crawlPlan.Limits.DeadlineDuration()
+	if err != nil {
+		return err
+	}
+	opts := processor.SeedOptions{
+		PlanId:      planId,
+		Refresh:     crawlPlan.Refresh,
+		Incremental: crawlPlan.Incremental,
+		Priority:    crawlPlan.Priority,
+		Budget: processor.Budget{
+			MaxPages:   crawlPlan.Limits.MaxPages,
+			MaxRecords: crawlPlan.Limits.MaxRecords,
+			Deadline:   deadline,
+		},
+	}
+	for _, query := range queries {
+		err = searchPro.CollectionStart(ctx, dbw, &query, opts)
+		if err != nil {
+			fmt.Printf("Failed: %s", fmt.Errorf("failed to start collection: %v", err))
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	collections, err := dbw.GetPlanCollections(ctx, planId)
+	if err != nil {
+		return err
+	}
+	pages := 0
+	for _, c := range collections {
+		pages += c.TotalPages
+	}
+	fmt.Printf("Plan %q has produced %d collections (%d pages)\n", crawlPlan.Name, len(collections), pages)
+	return nil
+}
@@ -23,19 +23,59 @@ GO
 USE $(DB_NAME);
 GO
 
+CREATE TABLE CrawlPlans (
+    PlanId INT IDENTITY(1,1) PRIMARY KEY,
+    Name NVARCHAR(200) NOT NULL,
+    Definition NVARCHAR(MAX) NOT NULL,
+    DefinitionHash AS CAST(HASHBYTES('SHA2_256', Definition) AS VARBINARY(32)) PERSISTED,
+    CreatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET(),
+
+    INDEX IX_CrawlPlans_Name (Name, DefinitionHash)
+);
+GO
+
 CREATE TABLE Collections (
     CollectionId int IDENTITY(1,1) PRIMARY KEY,
+    PlanId INT NULL,
     BatchSize INT NOT NULL,
     IsComplete BIT DEFAULT 0,
     StartedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET(),
     CompletedAt DATETIMEOFFSET NULL,
     TotalPages INT,
+    ExpectedRecords INT NULL, -- search total when the collection was seeded
+    CollectedRecords INT NULL, -- records returned by collected pages, set on finalization
+    DeadPages INT NULL, -- pages given up on, set on finalization
+    ObservedRecords INT NULL, -- latest search total reported while fetching pages
+    DriftRecords INT NOT NULL DEFAULT 0, -- largest difference seen between ObservedRecords and ExpectedRecords
+    CoverageSuspect BIT NOT NULL DEFAULT 0, -- drift exceeded the tolerance, so records may have been missed
     SourceUrl NVARCHAR(MAX),
+    QueryKey NVARCHAR(MAX) NULL, -- normalized search, see SearchParams.QueryKey
+    QueryKeyHash AS CAST(HASHBYTES('SHA2_256', QueryKey) AS VARBINARY(32)) PERSISTED,
+    Scope NVARCHAR(100) NULL, -- what the search is confined to, e.g. cemetery:42; NULL for site-wide searches
+    Mode NVARCHAR(20) NOT NULL DEFAULT N'full', -- full, or incremental to collect only records changed since Since
+    Since DATETIMEOFFSET NULL, -- watermark an incremental collection started from
+    HighWater DATETIMEOFFSET NULL, -- newest record change time collected
+    MaxPages INT NULL, -- most pages that will be fetched, including the site's page cap
+    MaxRecords INT NULL, -- record budget; pages starting past it are not fetched
+    Deadline DATETIMEOFFSET NULL, -- pages still open after this are not fetched
+    TruncatedReason NVARCHAR(50) NULL, -- page_cap, max_pages, max_records or deadline when not every page was fetched
+    Priority INT NOT NULL DEFAULT 0, -- higher is scheduled sooner, see GetAndReservePageBatch
+    State NVARCHAR(20) NOT NULL DEFAULT N'active', -- active, paused or cancelled, see ControlCrawl
+    StateReason NVARCHAR(200) NULL, -- why the collection was last paused or cancelled
     CreatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET(),
-    UpdatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET()
+    UpdatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET(),
+
+    CONSTRAINT FK_Collections_CrawlPlans FOREIGN KEY (PlanId)
+        REFERENCES CrawlPlans (PlanId)
 );
 GO
 
+CREATE INDEX IX_Collections_QueryKey ON Collections (QueryKeyHash) INCLUDE (IsComplete);
+GO
+
+CREATE INDEX IX_Collections_Scope ON Collections (Scope) INCLUDE (IsComplete);
+GO
+
 CREATE TABLE Pages (
     PageId INT IDENTITY(1,1) PRIMARY KEY,
     CollectionId int NOT NULL,
@@ -44,6 +84,13 @@ CREATE TABLE Pages (
     Progress NVARCHAR(MAX),
     IsComplete BIT DEFAULT 0,
     RetryCount INT DEFAULT 0,
+    RecordCount INT NULL,
+    LastError NVARCHAR(1000) NULL, -- reason the last attempt failed
+    FailureClass NVARCHAR(20) NULL, -- retryable or permanent
+    FailureCause NVARCHAR(50) NULL, -- short cause of the last failure, e.g. http_500 or html
+    ArchiveKey NVARCHAR(64) NULL, -- raw response in the archive, see ARCHIVE_DIR
+    LeaseOwner NVARCHAR(100) NULL, -- worker processing the page, see GetAndReservePageBatch
+    LeaseExpiresAt DATETIMEOFFSET NULL,
     LastAttemptAt DATETIMEOFFSET,
     CreatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET(),
     UpdatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET(),
@@ -54,6 +101,87 @@ CREATE TABLE Pages (
 );
 GO
 
+CREATE UNIQUE INDEX UX_Pages_CollectionPage ON Pages (CollectionId, PageNumber);
+GO
+
+-- Every attempt at a page, successful or not, so failures can be analyzed
+-- per collection and over time, and a dead page's history survives the
+-- LastError of its final attempt
+CREATE TABLE PageAttempts (
+    PageAttemptId BIGINT IDENTITY(1,1) PRIMARY KEY,
+    PageId INT NOT NULL,
+    CollectionId INT NOT NULL,
+    Attempt INT NOT NULL, -- the page's RetryCount when it was attempted
+    Outcome NVARCHAR(20) NOT NULL, -- collected, failed or dead
+    StartedAt DATETIMEOFFSET NULL,
+    FinishedAt DATETIMEOFFSET NOT NULL DEFAULT SYSDATETIMEOFFSET(),
+    StatusCode INT NULL, -- of the last response received, if any
+    LatencyMs INT NULL, -- until that response's headers arrived
+    RecordCount INT NULL,
+    FailureClass NVARCHAR(20) NULL,
+    FailureCause NVARCHAR(50) NULL,
+    Error NVARCHAR(1000) NULL,
+    ArchiveKey NVARCHAR(64) NULL,
+
+    CONSTRAINT FK_PageAttempts_Pages FOREIGN KEY (PageId)
+        REFERENCES Pages (PageId)
+        ON DELETE CASCADE
+);
+GO
+
+CREATE INDEX IX_PageAttempts_Page ON PageAttempts (PageId, Attempt);
+GO
+
+CREATE INDEX IX_PageAttempts_Collection ON PageAttempts (CollectionId, FinishedAt) INCLUDE (Outcome, FailureCause);
+GO
+
+CREATE TABLE QueryPartitions (
+    PartitionId INT IDENTITY(1,1) PRIMARY KEY,
+    ParentPartitionId INT NULL,
+    SourceUrl NVARCHAR(MAX) NOT NULL,
+    QueryKey NVARCHAR(MAX) NOT NULL,
+    QueryKeyHash AS CAST(HASHBYTES('SHA2_256', QueryKey) AS VARBINARY(32)) PERSISTED,
+    TotalRecords INT NOT NULL,
+    Depth INT NOT NULL DEFAULT 0,
+    SplitOn NVARCHAR(50) NULL, -- field the children were narrowed on; NULL for leaves
+    CollectionId INT NULL, -- set on leaves that were seeded as a collection
+    IsTruncated BIT NOT NULL DEFAULT 0, -- leaf still exceeds the page cap, or children miss records
+    CreatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET(),
+
+    CONSTRAINT FK_QueryPartitions_Parent FOREIGN KEY (ParentPartitionId)
+        REFERENCES QueryPartitions (PartitionId),
+    CONSTRAINT FK_QueryPartitions_Collections FOREIGN KEY (CollectionId)
+        REFERENCES Collections (CollectionId)
+        ON DELETE SET NULL
+);
+GO
+
+CREATE INDEX IX_QueryPartitions_Parent ON QueryPartitions (ParentPartitionId, QueryKeyHash);
+GO
+
+-- High-water mark per search for incremental crawls, advanced when an
+-- incremental collection of the search finishes without dead pages
+CREATE TABLE QueryWatermarks (
+    QueryKeyHash VARBINARY(32) NOT NULL PRIMARY KEY,
+    QueryKey NVARCHAR(MAX) NOT NULL,
+    Watermark DATETIMEOFFSET NOT NULL,
+    CollectionId INT NULL, -- collection that last advanced the watermark
+    UpdatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET()
+);
+GO
+
+-- Crawl-wide switches shared by every worker; there is only ever one row
+CREATE TABLE CrawlControl (
+    Id INT NOT NULL PRIMARY KEY CHECK (Id = 1),
+    Paused BIT NOT NULL DEFAULT 0, -- no pages are reserved while set
+    Reason NVARCHAR(200) NULL,
+    UpdatedAt DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET()
+);
+GO
+
+INSERT INTO CrawlControl (Id) VALUES (1);
+GO
+
 CREATE TABLE Memorials (
     MemorialId BIGINT PRIMARY KEY,
     CollectionId INT NOT NULL,
@@ -88,9 +216,22 @@ ON Pages (IsComplete, Progress, LastAttemptAt)
 INCLUDE (PageId, CollectionId, PageNumber, SearchUrl);
 GO
 
+CREATE INDEX IX_Pages_LeaseOwner ON Pages (LeaseOwner) WHERE LeaseOwner IS NOT NULL;
+GO
+
+-- Open pages in page order, so reservations rank them without reading the
+-- collected ones
+CREATE INDEX IX_Pages_Open ON Pages (CollectionId, PageNumber) INCLUDE (LeaseExpiresAt) WHERE IsComplete = 0;
+GO
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON dbo.CrawlPlans TO [$(APP_USER)];
 GRANT SELECT, INSERT, UPDATE, DELETE ON dbo.Collections TO [$(APP_USER)];
 GRANT SELECT, INSERT, UPDATE, DELETE ON dbo.Pages TO [$(APP_USER)];
+GRANT SELECT, INSERT ON dbo.PageAttempts TO [$(APP_USER)];
 GRANT SELECT, INSERT, UPDATE, DELETE ON dbo.Memorials TO [$(APP_USER)];
+GRANT SELECT, INSERT, UPDATE, DELETE ON dbo.QueryPartitions TO [$(APP_USER)];
+GRANT SELECT, INSERT, UPDATE, DELETE ON dbo.QueryWatermarks TO [$(APP_USER)];
+GRANT SELECT ON dbo.CrawlControl TO [$(APP_USER)];
 GO
 
 CREATE TYPE dbo.MemorialIdList AS TABLE (
@@ -181,7 +322,13 @@ BEGIN
         LastAttemptAt,
         SYSDATETIMEOFFSET(),
         SYSDATETIMEOFFSET()
-    FROM @Pages;
+    FROM @Pages p
+    -- Seeding is resumable, so pages that already exist are left alone
+    WHERE NOT EXISTS (
+        SELECT 1 FROM dbo.Pages existing
+        WHERE existing.CollectionId = p.CollectionId
+          AND existing.PageNumber = p.PageNumber
+    );
     
    
     SELECT @@ROWCOUNT AS RowsInserted;
@@ -190,8 +337,20 @@ GO
 
 CREATE PROCEDURE sp_StartNewCollection
 @BatchSize int = 100,
-@SourceUrl nvarchar(500),
-@StartedAt datetimeoffset = null
+@SourceUrl nvarchar(max),
+@StartedAt datetimeoffset = null,
+@PlanId int = null,
+@TotalPages int = 0,
+@ExpectedRecords int = null,
+@QueryKey nvarchar(max) = null,
+@Scope nvarchar(100) = null,
+@Mode nvarchar(20) = N'full',
+@Since datetimeoffset = null,
+@MaxPages int = null,
+@MaxRecords int = null,
+@Deadline datetimeoffset = null,
+@TruncatedReason nvarchar(50) = null,
+@Priority int = 0
 AS
 BEGIN
     SET NOCOUNT ON;
@@ -203,22 +362,44 @@ BEGIN
 
     -- Insert the new record (CollectionId will be auto-generated)
     INSERT INTO Collections (
+        PlanId,
         BatchSize,
         IsComplete,
         StartedAt,
         CompletedAt,
         TotalPages,
+        ExpectedRecords,
         SourceUrl,
+        QueryKey,
+        Scope,
+        Mode,
+        Since,
+        MaxPages,
+        MaxRecords,
+        Deadline,
+        TruncatedReason,
+        Priority,
         CreatedAt,
         UpdatedAt
     )
     VALUES (
+        @PlanId,
         @BatchSize,
         0,
         @StartedAt,
         null,
-        0,
+        @TotalPages,
+        @ExpectedRecords,
         @SourceUrl,
+        @QueryKey,
+        @Scope,
+        @Mode,
+        @Since,
+        @MaxPages,
+        @MaxRecords,
+        @Deadline,
+        @TruncatedReason,
+        @Priority,
         SYSDATETIMEOFFSET(),
         SYSDATETIMEOFFSET()
     );
@@ -232,27 +413,379 @@ BEGIN
 END;
 GO
 
+CREATE PROCEDURE dbo.RegisterCrawlPlan
+    @Name NVARCHAR(200),
+    @Definition NVARCHAR(MAX)
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    DECLARE @PlanId INT;
+
+    -- Reuse the existing row when the same plan is run again unchanged
+    SELECT TOP(1) @PlanId = PlanId
+    FROM CrawlPlans
+    WHERE Name = @Name
+      AND DefinitionHash = CAST(HASHBYTES('SHA2_256', @Definition) AS VARBINARY(32))
+    ORDER BY PlanId DESC;
+
+    IF @PlanId IS NULL
+    BEGIN
+        INSERT INTO CrawlPlans (Name, Definition) VALUES (@Name, @Definition);
+        SET @PlanId = SCOPE_IDENTITY();
+    END
+
+    SELECT @PlanId AS NewRecordID;
+END
+GO
+
+CREATE PROCEDURE dbo.InsertQueryPartition
+    @ParentPartitionId INT = NULL,
+    @SourceUrl NVARCHAR(MAX),
+    @QueryKey NVARCHAR(MAX),
+    @TotalRecords INT,
+    @Depth INT,
+    @SplitOn NVARCHAR(50) = NULL,
+    @CollectionId INT = NULL,
+    @IsTruncated BIT = 0
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    INSERT INTO QueryPartitions (
+        ParentPartitionId,
+        SourceUrl,
+        QueryKey,
+        TotalRecords,
+        Depth,
+        SplitOn,
+        CollectionId,
+        IsTruncated
+    )
+    VALUES (
+        @ParentPartitionId,
+        @SourceUrl,
+        @QueryKey,
+        @TotalRecords,
+        @Depth,
+        @SplitOn,
+        @CollectionId,
+        @IsTruncated
+    );
+
+    SELECT CAST(SCOPE_IDENTITY() AS INT) AS NewRecordID;
+END
+GO
+
+-- A search is complete when every leaf of its most recent partition tree that
+-- returned records has a completed collection
+CREATE PROCEDURE dbo.IsQueryComplete
+    @QueryKey NVARCHAR(MAX)
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    DECLARE @RootId INT;
+
+    SELECT TOP(1) @RootId = PartitionId
+    FROM QueryPartitions
+    WHERE QueryKeyHash = CAST(HASHBYTES('SHA2_256', @QueryKey) AS VARBINARY(32))
+      AND QueryKey = @QueryKey
+      AND ParentPartitionId IS NULL
+    ORDER BY PartitionId DESC;
+
+    IF @RootId IS NULL
+    BEGIN
+        SELECT CAST(0 AS BIT) AS IsComplete;
+        RETURN;
+    END;
+
+    WITH Tree AS (
+        SELECT PartitionId, SplitOn, CollectionId, TotalRecords
+        FROM QueryPartitions
+        WHERE PartitionId = @RootId
+        UNION ALL
+        SELECT child.PartitionId, child.SplitOn, child.CollectionId, child.TotalRecords
+        FROM QueryPartitions child
+        JOIN Tree parent ON child.ParentPartitionId = parent.PartitionId
+    )
+    SELECT CAST(CASE WHEN EXISTS (
+        SELECT 1
+        FROM Tree t
+        LEFT JOIN Collections c ON c.CollectionId = t.CollectionId
+        WHERE t.SplitOn IS NULL
+          AND t.TotalRecords > 0
+          AND (ISNULL(c.IsComplete, 0) = 0 OR c.CoverageSuspect = 1)
+    ) THEN 0 ELSE 1 END AS BIT) AS IsComplete
+    OPTION (MAXRECURSION 0);
+END
+GO
+
+-- Marks a collection complete once every seeded page is either collected or
+-- dead-lettered, recording how many records were collected against the total
+-- the search reported when it was seeded. A cancelled collection will never
+-- have the rest of its pages seeded, so it is finalized with what it has.
+CREATE PROCEDURE dbo.FinalizeCollection
+    @CollectionId INT,
+    @Finalized BIT = 0 OUTPUT
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    UPDATE c
+    SET
+        IsComplete = 1,
+        CompletedAt = SYSDATETIMEOFFSET(),
+        UpdatedAt = SYSDATETIMEOFFSET(),
+        CollectedRecords = p.CollectedRecords,
+        DeadPages = p.DeadPages
+    FROM Collections c
+    CROSS APPLY (
+        SELECT
+            COUNT(*) AS SeededPages,
+            SUM(CASE WHEN IsComplete = 0 AND ISNULL(Progress, '') <> N'dead' THEN 1 ELSE 0 END) AS OpenPages,
+            SUM(CASE WHEN Progress = N'dead' THEN 1 ELSE 0 END) AS DeadPages,
+            ISNULL(SUM(CASE WHEN IsComplete = 1 THEN RecordCount ELSE 0 END), 0) AS CollectedRecords
+        FROM Pages
+        WHERE CollectionId = c.CollectionId
+    ) p
+    WHERE c.CollectionId = @CollectionId
+      AND ISNULL(c.IsComplete, 0) = 0
+      AND (p.SeededPages >= ISNULL(c.TotalPages, 0) OR c.State = N'cancelled')
+      AND p.OpenPages = 0;
+
+    SET @Finalized = CAST(@@ROWCOUNT AS BIT);
+
+    -- A clean incremental run moves its search's watermark up to the newest
+    -- record it collected. Runs that stopped short of their budget or the
+    -- page cap did not see every changed record, so they leave it alone.
+    -- Skipped pages do not count against a run: they are only skipped once
+    -- a sorted search has reached records older than the watermark.
+    IF @Finalized = 1
+    BEGIN
+        MERGE QueryWatermarks AS target
+        USING (
+            SELECT QueryKeyHash, QueryKey, HighWater
+            FROM Collections
+            WHERE CollectionId = @CollectionId
+              AND Mode = N'incremental'
+              AND State <> N'cancelled'
+              AND DeadPages = 0
+              AND TruncatedReason IS NULL
+              AND HighWater IS NOT NULL
+              AND QueryKey IS NOT NULL
+              AND NOT EXISTS (
+                  SELECT 1 FROM Pages
+                  WHERE CollectionId = @CollectionId AND Progress = N'truncated')
+              AND NOT EXISTS (
+                  SELECT 1 FROM QueryPartitions
+                  WHERE CollectionId = @CollectionId AND IsTruncated = 1)
+        ) AS source ON target.QueryKeyHash = source.QueryKeyHash
+        WHEN MATCHED AND source.HighWater > target.Watermark THEN
+            UPDATE SET
+                Watermark = source.HighWater,
+                CollectionId = @CollectionId,
+                UpdatedAt = SYSDATETIMEOFFSET()
+        WHEN NOT MATCHED THEN
+            INSERT (QueryKeyHash, QueryKey, Watermark, CollectionId)
+            VALUES (source.QueryKeyHash, source.QueryKey, source.HighWater, @CollectionId);
+    END
+END
+GO
+
+-- Records the search total reported while a collection's pages were being
+-- fetched. Growth extends TotalPages so the extra pages can be appended, and
+-- drift beyond @Tolerance records flags the collection for a re-crawl, since
+-- shifted skip windows may have dropped or repeated records
+CREATE PROCEDURE dbo.RecordCollectionDrift
+    @CollectionId INT,
+    @ObservedRecords INT,
+    @Tolerance INT = 0
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    DECLARE @PreviousPages INT, @TotalPages INT, @CoverageSuspect BIT;
+
+    UPDATE c
+    SET
+        @PreviousPages = ISNULL(c.TotalPages, 0),
+        ObservedRecords = @ObservedRecords,
+        DriftRecords = CASE WHEN d.Drift > c.DriftRecords THEN d.Drift ELSE c.DriftRecords END,
+        @CoverageSuspect = CoverageSuspect = CASE WHEN d.Drift > @Tolerance THEN 1 ELSE c.CoverageSuspect END,
+        @TotalPages = TotalPages = CASE
+            WHEN d.ObservedPages > ISNULL(c.TotalPages, 0) THEN d.ObservedPages
+            ELSE c.TotalPages
+        END,
+        TruncatedReason = CASE
+            WHEN d.ObservedPages < d.AllPages THEN ISNULL(c.TruncatedReason, N'max_pages')
+            ELSE c.TruncatedReason
+        END,
+        UpdatedAt = SYSDATETIMEOFFSET()
+    FROM Collections c
+    CROSS APPLY (
+        SELECT
+            ABS(@ObservedRecords - ISNULL(c.ExpectedRecords, @ObservedRecords)) AS Drift,
+            (@ObservedRecords + c.BatchSize - 1) / c.BatchSize AS AllPages
+    ) a
+    CROSS APPLY (
+        -- growth never extends a collection past its page budget
+        SELECT
+            a.Drift,
+            a.AllPages,
+            CASE WHEN c.MaxPages < a.AllPages THEN c.MaxPages ELSE a.AllPages END AS ObservedPages
+    ) d
+    WHERE c.CollectionId = @CollectionId
+      AND c.State = N'active'
+      AND ISNULL(c.IsComplete, 0) = 0;
+
+    IF @@ROWCOUNT = 0
+    BEGIN
+        -- a finished, paused or cancelled collection is left as it is, since
+        -- pages appended to it might never be reserved
+        IF NOT EXISTS (SELECT 1 FROM Collections WHERE CollectionId = @CollectionId)
+        BEGIN
+            RAISERROR('Collection with ID %d not found', 16, 1, @CollectionId);
+            RETURN;
+        END
+        SELECT CAST(0 AS BIT) AS Updated, 0 AS PreviousPages, 0 AS TotalPages, CAST(0 AS BIT) AS CoverageSuspect;
+        RETURN;
+    END
+
+    SELECT CAST(1 AS BIT) AS Updated, @PreviousPages AS PreviousPages, @TotalPages AS TotalPages, @CoverageSuspect AS CoverageSuspect;
+END
+GO
+
+-- Returns the newest record date a clean incremental run of the search has
+-- collected, which later runs stop at; no row if there has been none
+CREATE PROCEDURE dbo.GetQueryWatermark
+    @QueryKey NVARCHAR(MAX)
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    SELECT Watermark
+    FROM QueryWatermarks
+    WHERE QueryKeyHash = CAST(HASHBYTES('SHA2_256', @QueryKey) AS VARBINARY(32));
+END
+GO
+
+-- Compares the records a split partition's children report with the total
+-- the partition itself reported. Children only extend the name with A-Z, so
+-- names continuing with anything else (an apostrophe, hyphen, space, digit or
+-- accented letter) match the parent but no child. When the children fall
+-- short the partition is flagged truncated.
+CREATE PROCEDURE dbo.CheckPartitionCoverage
+    @PartitionId INT
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    DECLARE @ChildRecords INT = (
+        SELECT ISNULL(SUM(TotalRecords), 0)
+        FROM (
+            SELECT TotalRecords,
+                ROW_NUMBER() OVER (PARTITION BY QueryKeyHash ORDER BY PartitionId DESC) AS Latest
+            FROM QueryPartitions
+            WHERE ParentPartitionId = @PartitionId
+        ) children
+        WHERE Latest = 1
+    );
+
+    UPDATE QueryPartitions
+    SET IsTruncated = 1
+    WHERE PartitionId = @PartitionId
+      AND TotalRecords > @ChildRecords;
+
+    SELECT TotalRecords, @ChildRecords AS ChildRecords
+    FROM QueryPartitions
+    WHERE PartitionId = @PartitionId;
+END
+GO
+
+-- Points a partition at a new collection when its search is re-crawled
+CREATE PROCEDURE dbo.SetPartitionCollection
+    @PartitionId INT,
+    @CollectionId INT,
+    @TotalRecords INT
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    UPDATE QueryPartitions
+    SET CollectionId = @CollectionId,
+        TotalRecords = @TotalRecords
+    WHERE PartitionId = @PartitionId;
+END
+GO
+
 CREATE PROCEDURE dbo.MarkPageCollected
-    @PageID INT
+    @PageID INT,
+    @RecordCount INT = NULL,
+    @NewestRecord DATETIMEOFFSET = NULL,
+    @Exhausted BIT = 0,
+    @ArchiveKey NVARCHAR(64) = NULL,
+    @StartedAt DATETIMEOFFSET = NULL,
+    @StatusCode INT = NULL,
+    @LatencyMs INT = NULL
 AS
 BEGIN
     SET NOCOUNT ON;
     
     BEGIN TRY
+        DECLARE @CollectionId INT, @Attempt INT;
+
         UPDATE Pages 
         SET 
             IsComplete = 1,
             Progress = 'completed',
+            RecordCount = @RecordCount,
+            ArchiveKey = COALESCE(@ArchiveKey, ArchiveKey),
+            LeaseOwner = NULL,
+            LeaseExpiresAt = NULL,
             UpdatedAt = SYSDATETIMEOFFSET(),
-            LastAttemptAt = SYSDATETIMEOFFSET()
-        WHERE PageId = @PageID;
+            LastAttemptAt = SYSDATETIMEOFFSET(),
+            @CollectionId = CollectionId,
+            @Attempt = ISNULL(RetryCount, 0)
+        WHERE PageId = @PageID
+          AND IsComplete = 0
+          AND ISNULL(Progress, '') <> N'dead';
         
         -- Check if the record was actually updated
         IF @@ROWCOUNT = 0
         BEGIN
+            -- the page was closed while it was being fetched, as in
+            -- MarkPageFailed, so its outcome no longer counts
+            IF EXISTS (SELECT 1 FROM Pages WHERE PageId = @PageID AND (IsComplete = 1 OR Progress = N'dead'))
+                RETURN;
             RAISERROR('Page with ID %d not found', 16, 1, @PageID);
             RETURN;
         END
+
+        INSERT INTO PageAttempts (PageId, CollectionId, Attempt, Outcome, StartedAt, StatusCode, LatencyMs, RecordCount, ArchiveKey)
+        VALUES (@PageID, @CollectionId, @Attempt, N'collected', @StartedAt, @StatusCode, @LatencyMs, @RecordCount, @ArchiveKey);
+
+        IF @NewestRecord IS NOT NULL
+            UPDATE Collections
+            SET HighWater = @NewestRecord
+            WHERE CollectionId = @CollectionId
+              AND (HighWater IS NULL OR HighWater < @NewestRecord);
+
+        -- An incremental page holding only records older than the watermark
+        -- means every later page is older too, so they are not fetched
+        IF @Exhausted = 1
+            UPDATE later
+            SET IsComplete = 1,
+                Progress = N'skipped',
+                UpdatedAt = SYSDATETIMEOFFSET()
+            FROM Pages later
+            JOIN Pages collected ON collected.PageId = @PageID
+            WHERE later.CollectionId = collected.CollectionId
+              AND later.PageNumber > collected.PageNumber
+              AND later.IsComplete = 0;
+
+        -- Finalize the collection if this was its last open page
+        EXEC dbo.FinalizeCollection @CollectionId = @CollectionId;
         
     END TRY
     BEGIN CATCH
@@ -262,50 +795,467 @@ BEGIN
 END
 GO
 
+-- Reserves up to @BatchSize pages for @Owner until their lease expires.
+-- Pages whose lease has run out, because the worker holding them crashed or
+-- was stopped, are reserved again as if they had never been handed out.
+-- Pages already attempted @MaxAttempts times are dead-lettered instead.
+--
+-- @Policy decides which collections' pages go first:
+--   priority     highest Priority first, oldest collection first within it
+--   round_robin  one page from each collection in turn, with a collection of
+--                Priority n getting n + 1 pages a round
+--   oldest       oldest collection first
+-- Pages within a collection are always reserved in page order. Nothing is
+-- reserved from paused collections, or at all while the crawl is paused.
 CREATE PROCEDURE dbo.GetAndReservePageBatch
-    @BatchSize INT = 100
+    @BatchSize INT = 100,
+    @Owner NVARCHAR(100) = NULL,
+    @LeaseSeconds INT = 300,
+    @MaxAttempts INT = NULL,
+    @Policy NVARCHAR(20) = N'priority'
 AS
 BEGIN
     SET NOCOUNT ON;
-    
-    UPDATE TOP(@BatchSize) Pages
-    SET 
-        Progress = N'processing',
-        UpdatedAt = SYSDATETIMEOFFSET(),
-        LastAttemptAt = SYSDATETIMEOFFSET(),
-        RetryCount = ISNULL(RetryCount, 0) + 1
-    OUTPUT 
-        INSERTED.PageId,
-        INSERTED.CollectionId,
-        INSERTED.PageNumber,
-        INSERTED.SearchUrl,
-        INSERTED.Progress,
-        INSERTED.IsComplete,
-        INSERTED.RetryCount,
-        INSERTED.LastAttemptAt,
-        INSERTED.CreatedAt,
-        INSERTED.UpdatedAt
-    WHERE 
-        IsComplete = 0 
-        AND (Progress IS NULL OR Progress = 'pending' OR Progress = 'failed')
+
+    IF @Policy NOT IN (N'priority', N'round_robin', N'oldest')
+    BEGIN
+        RAISERROR (N'Unknown scheduling policy %s', 16, 1, @Policy);
+        RETURN;
+    END
+
+    DECLARE @Now DATETIMEOFFSET = SYSDATETIMEOFFSET();
+
+    DECLARE @Dead TABLE (
+        PageId INT NOT NULL,
+        CollectionId INT NOT NULL,
+        Attempt INT NOT NULL,
+        StartedAt DATETIMEOFFSET NULL,
+        LeaseExpired BIT NOT NULL
+    );
+
+    IF @MaxAttempts > 0
+        UPDATE p
+        SET
+            Progress = N'dead',
+            -- an expired lease means the last attempt never reported back
+            FailureCause = CASE WHEN p.Progress = N'processing' THEN N'lease_expired' ELSE p.FailureCause END,
+            LeaseOwner = NULL,
+            LeaseExpiresAt = NULL,
+            UpdatedAt = SYSDATETIMEOFFSET()
+        OUTPUT INSERTED.PageId, INSERTED.CollectionId, ISNULL(INSERTED.RetryCount, 0), INSERTED.LastAttemptAt,
+            CASE WHEN DELETED.Progress = N'processing' THEN 1 ELSE 0 END
+        INTO @Dead
+        FROM Pages p
+        WHERE p.IsComplete = 0
+          AND ISNULL(p.RetryCount, 0) >= @MaxAttempts
+          AND (p.Progress IS NULL OR p.Progress = 'pending' OR p.Progress = 'failed'
+               OR (p.Progress = 'processing' AND (p.LeaseExpiresAt IS NULL OR p.LeaseExpiresAt <= @Now)));
+
+    -- Failed pages already have their last attempt recorded, but one whose
+    -- lease expired never reported back, so its attempt is recorded here
+    INSERT INTO PageAttempts (PageId, CollectionId, Attempt, Outcome, StartedAt, FailureClass, FailureCause, Error)
+    SELECT PageId, CollectionId, Attempt, N'dead', StartedAt, N'retryable', N'lease_expired',
+        N'lease expired before the attempt reported back'
+    FROM @Dead
+    WHERE LeaseExpired = 1;
+
+    -- Pages outside their collection's budget are closed instead of fetched
+    DECLARE @Truncated TABLE (CollectionId INT NOT NULL, Reason NVARCHAR(50) NOT NULL);
+
+    UPDATE p
+    SET
+        IsComplete = 1,
+        Progress = N'truncated',
+        UpdatedAt = SYSDATETIMEOFFSET()
+    OUTPUT INSERTED.CollectionId, b.Reason INTO @Truncated
+    FROM Pages p
+    JOIN Collections c ON c.CollectionId = p.CollectionId
+    CROSS APPLY (
+        SELECT CASE
+            WHEN c.Deadline <= SYSDATETIMEOFFSET() THEN N'deadline'
+            WHEN p.PageNumber > c.MaxPages THEN N'max_pages'
+            WHEN (p.PageNumber - 1) * c.BatchSize >= c.MaxRecords THEN N'max_records'
+        END AS Reason
+    ) b
+    WHERE p.IsComplete = 0
+      AND (p.Progress IS NULL OR p.Progress = 'pending' OR p.Progress = 'failed'
+           OR (p.Progress = 'processing' AND (p.LeaseExpiresAt IS NULL OR p.LeaseExpiresAt <= @Now)))
+      AND b.Reason IS NOT NULL;
+
+    IF EXISTS (SELECT 1 FROM @Truncated)
+    BEGIN
+        UPDATE c
+        SET TruncatedReason = t.Reason,
+            UpdatedAt = SYSDATETIMEOFFSET()
+        FROM Collections c
+        JOIN (
+            SELECT CollectionId, MIN(Reason) AS Reason
+            FROM @Truncated
+            GROUP BY CollectionId
+        ) t ON t.CollectionId = c.CollectionId
+        WHERE c.TruncatedReason IS NULL;
+    END
+
+    -- Collections that lost pages to either may now be finished
+    IF EXISTS (SELECT 1 FROM @Truncated) OR EXISTS (SELECT 1 FROM @Dead)
+    BEGIN
+        DECLARE @ClosedId INT;
+        DECLARE closed CURSOR LOCAL FAST_FORWARD FOR
+            SELECT CollectionId FROM @Truncated
+            UNION
+            SELECT CollectionId FROM @Dead;
+        OPEN closed;
+        FETCH NEXT FROM closed INTO @ClosedId;
+        WHILE @@FETCH_STATUS = 0
+        BEGIN
+            EXEC dbo.FinalizeCollection @CollectionId = @ClosedId;
+            FETCH NEXT FROM closed INTO @ClosedId;
+        END
+        CLOSE closed;
+        DEALLOCATE closed;
+    END
+
+    -- Candidates are ranked without lock hints, so the ranking does not hold
+    -- locks on every open page or hide them from other workers, and are then
+    -- reserved with UPDLOCK/READPAST after checking each is still open. If
+    -- another worker took every candidate in between, the ranking is redone.
+    DECLARE @Candidates TABLE (PageId INT PRIMARY KEY, Ord INT NOT NULL);
+    DECLARE @Reserved TABLE (PageId INT PRIMARY KEY);
+    DECLARE @Pass INT = 0;
+
+    WHILE @Pass < 3
+        AND NOT EXISTS (SELECT 1 FROM @Reserved)
+        AND NOT EXISTS (SELECT 1 FROM CrawlControl WHERE Paused = 1)
+    BEGIN
+        SET @Pass += 1;
+        DELETE FROM @Candidates;
+
+        INSERT INTO @Candidates (PageId, Ord)
+        SELECT TOP(@BatchSize)
+            e.PageId,
+            ROW_NUMBER() OVER (ORDER BY
+                CASE WHEN @Policy = N'priority' THEN e.Priority END DESC,
+                CASE WHEN @Policy = N'round_robin' THEN e.CollectionRank * 1.0 / CASE WHEN e.Priority > 0 THEN e.Priority + 1 ELSE 1 END END,
+                e.CollectionCreatedAt,
+                e.CollectionId,
+                e.PageNumber
+            ) AS Ord
+        FROM (
+            SELECT
+                cp.PageId,
+                cp.CollectionId,
+                cp.PageNumber,
+                cc.Priority,
+                cc.CreatedAt AS CollectionCreatedAt,
+                ROW_NUMBER() OVER (PARTITION BY cp.CollectionId ORDER BY cp.PageNumber) AS CollectionRank
+            FROM Pages cp
+            JOIN Collections cc ON cc.CollectionId = cp.CollectionId
+            WHERE 
+                cc.State = N'active'
+                AND cp.IsComplete = 0 
+                AND (cp.Progress IS NULL OR cp.Progress = 'pending' OR cp.Progress = 'failed'
+                     -- pages reserved before leases existed have no expiry
+                     OR (cp.Progress = 'processing' AND (cp.LeaseExpiresAt IS NULL OR cp.LeaseExpiresAt <= @Now)))
+        ) e
+        ORDER BY Ord;
+
+        IF @@ROWCOUNT = 0
+            BREAK;
+
+        UPDATE p
+        SET 
+            Progress = N'processing',
+            LeaseOwner = @Owner,
+            LeaseExpiresAt = DATEADD(SECOND, @LeaseSeconds, @Now),
+            UpdatedAt = SYSDATETIMEOFFSET(),
+            LastAttemptAt = SYSDATETIMEOFFSET(),
+            RetryCount = ISNULL(p.RetryCount, 0) + 1
+        OUTPUT INSERTED.PageId INTO @Reserved
+        FROM Pages p WITH (UPDLOCK, READPAST, ROWLOCK)
+        JOIN @Candidates b ON b.PageId = p.PageId
+        JOIN Collections c ON c.CollectionId = p.CollectionId
+        WHERE c.State = N'active'
+          AND p.IsComplete = 0
+          AND (p.Progress IS NULL OR p.Progress = 'pending' OR p.Progress = 'failed'
+               OR (p.Progress = 'processing' AND (p.LeaseExpiresAt IS NULL OR p.LeaseExpiresAt <= @Now)));
+    END
+
+    SELECT
+        p.PageId,
+        p.CollectionId,
+        p.PageNumber,
+        p.SearchUrl,
+        p.Progress,
+        p.IsComplete,
+        p.RetryCount,
+        p.LastAttemptAt,
+        p.CreatedAt,
+        p.UpdatedAt,
+        -- the total the page's skip window was computed from, for drift checks
+        ISNULL(c.ObservedRecords, c.ExpectedRecords) AS ExpectedRecords,
+        c.Mode,
+        c.Since
+    FROM @Reserved r
+    JOIN @Candidates b ON b.PageId = r.PageId
+    JOIN Pages p ON p.PageId = r.PageId
+    JOIN Collections c ON c.CollectionId = p.CollectionId
+    ORDER BY b.Ord;
+END
+GO
+
+-- Changes the priority of a collection, or of every unfinished collection a
+-- plan seeded, for pages that have yet to be reserved
+CREATE PROCEDURE dbo.SetCollectionPriority
+    @Priority INT,
+    @CollectionId INT = NULL,
+    @PlanId INT = NULL
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    UPDATE Collections
+    SET Priority = @Priority,
+        UpdatedAt = SYSDATETIMEOFFSET()
+    WHERE (CollectionId = @CollectionId OR PlanId = @PlanId)
+      AND ISNULL(IsComplete, 0) = 0;
+
+    SELECT @@ROWCOUNT AS Updated;
+END
+GO
+
+-- Extends every lease @Owner still holds, so pages a live worker is busy
+-- with are not reclaimed
+CREATE PROCEDURE dbo.RenewPageLeases
+    @Owner NVARCHAR(100),
+    @LeaseSeconds INT = 300
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    UPDATE Pages
+    SET LeaseExpiresAt = DATEADD(SECOND, @LeaseSeconds, SYSDATETIMEOFFSET())
+    WHERE LeaseOwner = @Owner
+      AND Progress = N'processing'
+      AND IsComplete = 0;
+
+    SELECT @@ROWCOUNT AS Renewed;
+END
+GO
+
+-- Hands the pages @Owner still holds back to the queue when it shuts down,
+-- rather than leaving them for their leases to expire
+CREATE PROCEDURE dbo.ReleasePageLeases
+    @Owner NVARCHAR(100),
+    @PageId INT = NULL
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    UPDATE Pages
+    SET Progress = N'pending',
+        LeaseOwner = NULL,
+        LeaseExpiresAt = NULL,
+        UpdatedAt = SYSDATETIMEOFFSET()
+    WHERE LeaseOwner = @Owner
+      AND (@PageId IS NULL OR PageId = @PageId)
+      AND Progress = N'processing'
+      AND IsComplete = 0;
+
+    SELECT @@ROWCOUNT AS Released;
 END
 GO
 
+-- Permanent failures will never succeed, and pages that have used up
+-- @MaxAttempts are not expected to, so either is dead-lettered and its
+-- collection allowed to finish without it
 CREATE PROCEDURE dbo.MarkPageFailed
-    @PageID INT
+    @PageID INT,
+    @Reason NVARCHAR(1000) = NULL,
+    @FailureClass NVARCHAR(20) = N'retryable',
+    @ArchiveKey NVARCHAR(64) = NULL,
+    @FailureCause NVARCHAR(50) = NULL,
+    @MaxAttempts INT = NULL,
+    @StartedAt DATETIMEOFFSET = NULL,
+    @StatusCode INT = NULL,
+    @LatencyMs INT = NULL
 AS
 BEGIN
     SET NOCOUNT ON;
 
+    DECLARE @CollectionId INT, @Attempt INT, @Progress NVARCHAR(20);
+
     UPDATE Pages WITH (ROWLOCK) 
-    SET  Progress      = N'failed',
+    SET  @Progress     = Progress = CASE
+                             WHEN @FailureClass = N'permanent' THEN N'dead'
+                             WHEN @MaxAttempts > 0 AND ISNULL(RetryCount, 0) >= @MaxAttempts THEN N'dead'
+                             ELSE N'failed'
+                         END,
+         @Attempt      = ISNULL(RetryCount, 0),
+         LastError     = @Reason,
+         FailureClass  = @FailureClass,
+         FailureCause  = @FailureCause,
+         ArchiveKey    = COALESCE(@ArchiveKey, ArchiveKey),
+         LeaseOwner    = NULL,
+         LeaseExpiresAt = NULL,
          UpdatedAt     = SYSDATETIMEOFFSET(),
-         LastAttemptAt = SYSDATETIMEOFFSET()
+         LastAttemptAt = SYSDATETIMEOFFSET(),
+         @CollectionId = CollectionId
     WHERE PageId    = @PageID
-      AND IsComplete = 0;
+      AND IsComplete = 0
+      AND ISNULL(Progress, '') <> N'dead';
 
     IF @@ROWCOUNT = 0
-        RAISERROR (N'Page %d not found or already complete', 16, 1, @PageID);
+    BEGIN
+        -- the page was closed while it was being fetched, e.g. because its
+        -- collection was cancelled, it fell outside the collection's budget
+        -- or its lease expired and it was dead-lettered
+        IF EXISTS (SELECT 1 FROM Pages WHERE PageId = @PageID AND (IsComplete = 1 OR Progress = N'dead'))
+            RETURN;
+        RAISERROR (N'Page %d not found', 16, 1, @PageID);
+        RETURN;
+    END
+
+    INSERT INTO PageAttempts (PageId, CollectionId, Attempt, Outcome, StartedAt, StatusCode, LatencyMs,
+        FailureClass, FailureCause, Error, ArchiveKey)
+    VALUES (@PageID, @CollectionId, @Attempt, @Progress, @StartedAt, @StatusCode, @LatencyMs,
+        @FailureClass, @FailureCause, @Reason, @ArchiveKey);
+
+    IF @Progress = N'dead'
+        EXEC dbo.FinalizeCollection @CollectionId = @CollectionId;
+END
+GO
+
+-- Returns dead pages to the queue with their attempts reset, once whatever
+-- killed them has been fixed. Either filter may be NULL to requeue every
+-- collection or every cause. Collections finalized without the pages are
+-- reopened.
+CREATE PROCEDURE dbo.RequeueDeadPages
+    @CollectionId INT = NULL,
+    @FailureCause NVARCHAR(50) = NULL
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    DECLARE @Requeued TABLE (CollectionId INT NOT NULL);
+
+    UPDATE p
+    SET Progress = N'pending',
+        RetryCount = 0,
+        UpdatedAt = SYSDATETIMEOFFSET()
+    OUTPUT INSERTED.CollectionId INTO @Requeued
+    FROM Pages p
+    JOIN Collections c ON c.CollectionId = p.CollectionId
+    WHERE p.Progress = N'dead'
+      AND p.IsComplete = 0
+      AND c.State <> N'cancelled'
+      AND (@CollectionId IS NULL OR p.CollectionId = @CollectionId)
+      AND (@FailureCause IS NULL OR p.FailureCause = @FailureCause);
+
+    UPDATE Collections
+    SET IsComplete = 0,
+        CompletedAt = NULL,
+        DeadPages = NULL,
+        UpdatedAt = SYSDATETIMEOFFSET()
+    WHERE CollectionId IN (SELECT CollectionId FROM @Requeued)
+      AND IsComplete = 1;
+
+    SELECT COUNT(*) AS Requeued FROM @Requeued;
+END
+GO
+
+-- Pauses, resumes or cancels @CollectionId, or the whole crawl when it is
+-- NULL, and returns how many collections were affected. Pausing stops new
+-- reservations; pages already reserved are left to finish. Cancelling closes
+-- every open page and finalizes the collection with TruncatedReason
+-- 'cancelled'. Cancelling the whole crawl cancels every unfinished
+-- collection, while pausing it sets CrawlControl so even collections seeded
+-- later wait until it is resumed.
+CREATE PROCEDURE dbo.ControlCrawl
+    @Action NVARCHAR(20),
+    @CollectionId INT = NULL,
+    @Reason NVARCHAR(200) = NULL
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    IF @Action NOT IN (N'pause', N'resume', N'cancel')
+    BEGIN
+        RAISERROR (N'Unknown crawl control action %s', 16, 1, @Action);
+        RETURN;
+    END
+
+    IF @CollectionId IS NULL AND @Action <> N'cancel'
+    BEGIN
+        UPDATE CrawlControl
+        SET Paused = CASE WHEN @Action = N'pause' THEN 1 ELSE 0 END,
+            Reason = @Reason,
+            UpdatedAt = SYSDATETIMEOFFSET();
+
+        SELECT @@ROWCOUNT AS Affected;
+        RETURN;
+    END
+
+    DECLARE @Affected TABLE (CollectionId INT NOT NULL);
+
+    UPDATE Collections
+    SET State = CASE @Action WHEN N'pause' THEN N'paused' WHEN N'resume' THEN N'active' ELSE N'cancelled' END,
+        StateReason = CASE WHEN @Action = N'resume' THEN StateReason ELSE @Reason END,
+        TruncatedReason = CASE WHEN @Action = N'cancel' THEN COALESCE(TruncatedReason, N'cancelled') ELSE TruncatedReason END,
+        UpdatedAt = SYSDATETIMEOFFSET()
+    OUTPUT INSERTED.CollectionId INTO @Affected
+    WHERE (@CollectionId IS NULL OR CollectionId = @CollectionId)
+      AND ISNULL(IsComplete, 0) = 0
+      AND State = CASE @Action WHEN N'resume' THEN N'paused' WHEN N'pause' THEN N'active' ELSE State END
+      AND State <> N'cancelled';
+
+    IF @Action = N'cancel'
+    BEGIN
+        UPDATE p
+        SET IsComplete = 1,
+            Progress = N'cancelled',
+            LeaseOwner = NULL,
+            LeaseExpiresAt = NULL,
+            UpdatedAt = SYSDATETIMEOFFSET()
+        FROM Pages p
+        JOIN @Affected a ON a.CollectionId = p.CollectionId
+        WHERE p.IsComplete = 0
+          AND ISNULL(p.Progress, '') <> N'dead';
+
+        DECLARE @CancelledId INT;
+        DECLARE cancelled CURSOR LOCAL FAST_FORWARD FOR
+            SELECT CollectionId FROM @Affected;
+        OPEN cancelled;
+        FETCH NEXT FROM cancelled INTO @CancelledId;
+        WHILE @@FETCH_STATUS = 0
+        BEGIN
+            EXEC dbo.FinalizeCollection @CollectionId = @CancelledId;
+            FETCH NEXT FROM cancelled INTO @CancelledId;
+        END
+        CLOSE cancelled;
+        DEALLOCATE cancelled;
+    END
+
+    SELECT COUNT(*) AS Affected FROM @Affected;
+END
+GO
+
+-- How many open pages are waiting on a pause, so a worker that finds
+-- nothing to reserve knows whether to wait for a resume or finish
+CREATE PROCEDURE dbo.GetPausedWork
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    DECLARE @CrawlPaused BIT = ISNULL((SELECT Paused FROM CrawlControl WHERE Id = 1), 0);
+
+    SELECT
+        @CrawlPaused AS CrawlPaused,
+        COUNT(*) AS PausedPages
+    FROM Pages p
+    JOIN Collections c ON c.CollectionId = p.CollectionId
+    WHERE p.IsComplete = 0
+      AND ISNULL(p.Progress, '') <> N'dead'
+      AND (@CrawlPaused = 1 OR c.State = N'paused');
 END
 GO
 
@@ -341,12 +1291,26 @@ GO
 
 GRANT EXECUTE ON dbo.MarkPageFailed TO [$(APP_USER)];
 GRANT EXECUTE ON dbo.MarkPageCollected TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.FinalizeCollection TO [$(APP_USER)];
 GRANT EXECUTE ON dbo.BulkInsertMemorials TO [$(APP_USER)];
 GRANT EXECUTE ON dbo.BulkInsertPages TO [$(APP_USER)];
 GRANT EXECUTE ON sp_StartNewCollection TO [$(APP_USER)];
 GRANT EXECUTE ON sp_GetUnseenMemorialIds TO [$(APP_USER)];
 GRANT EXECUTE ON sp_RecordSeenMemorialIds TO [$(APP_USER)];
 GRANT EXECUTE ON dbo.GetAndReservePageBatch TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.RenewPageLeases TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.ReleasePageLeases TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.RequeueDeadPages TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.SetCollectionPriority TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.ControlCrawl TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.GetPausedWork TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.InsertQueryPartition TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.RegisterCrawlPlan TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.IsQueryComplete TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.RecordCollectionDrift TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.SetPartitionCollection TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.CheckPartitionCoverage TO [$(APP_USER)];
+GRANT EXECUTE ON dbo.GetQueryWatermark TO [$(APP_USER)];
 
 --- ==========================================
 --- Dupe Tracking in separate script
@@ -470,4 +1434,91 @@ SELECT
     MAX(LastSeenAt) as LatestDuplicate
 FROM MemorialDuplicates
 GROUP BY CollectionId;
-GO
\ No newline at end of file
+GO
+
+-- Compares each partitioned search against the sum of its children so gaps
+-- in coverage can be spotted
+CREATE OR ALTER VIEW PartitionCoverage AS
+SELECT
+    p.PartitionId,
+    p.SourceUrl,
+    p.SplitOn,
+    p.TotalRecords,
+    SUM(c.TotalRecords) AS ChildRecords,
+    COUNT(c.PartitionId) AS ChildCount,
+    SUM(CAST(c.IsTruncated AS INT)) AS TruncatedChildren
+FROM QueryPartitions p
+JOIN QueryPartitions c ON c.ParentPartitionId = p.PartitionId
+GROUP BY p.PartitionId, p.SourceUrl, p.SplitOn, p.TotalRecords;
+GO
+
+-- Page progress per collection alongside its completion state
+CREATE OR ALTER VIEW CollectionProgress AS
+SELECT
+    c.CollectionId,
+    c.SourceUrl,
+    c.IsComplete,
+    c.CompletedAt,
+    c.TotalPages,
+    c.ExpectedRecords,
+    c.CollectedRecords,
+    c.ObservedRecords,
+    c.DriftRecords,
+    c.CoverageSuspect,
+    c.TruncatedReason,
+    c.State,
+    COUNT(p.PageId) AS SeededPages,
+    SUM(CASE WHEN p.IsComplete = 1 THEN 1 ELSE 0 END) AS CollectedPages,
+    SUM(CASE WHEN p.Progress = N'dead' THEN 1 ELSE 0 END) AS DeadPages,
+    ISNULL(SUM(CASE WHEN p.IsComplete = 1 THEN p.RecordCount ELSE 0 END), 0) AS RecordsSoFar
+FROM Collections c
+LEFT JOIN Pages p ON p.CollectionId = c.CollectionId
+GROUP BY c.CollectionId, c.SourceUrl, c.IsComplete, c.CompletedAt, c.TotalPages, c.ExpectedRecords, c.CollectedRecords,
+    c.ObservedRecords, c.DriftRecords, c.CoverageSuspect, c.TruncatedReason, c.State;
+GO
+
+-- Collection progress rolled up per scope, e.g. per cemetery in a cemetery crawl
+CREATE OR ALTER VIEW ScopeProgress AS
+SELECT
+    c.Scope,
+    COUNT(*) AS Collections,
+    SUM(CASE WHEN c.IsComplete = 1 THEN 1 ELSE 0 END) AS CompletedCollections,
+    SUM(ISNULL(c.ExpectedRecords, 0)) AS ExpectedRecords,
+    SUM(ISNULL(c.CollectedRecords, 0)) AS CollectedRecords
+FROM Collections c
+WHERE c.Scope IS NOT NULL
+GROUP BY c.Scope;
+GO
+
+-- Why each collection's dead pages died: permanent failures, or retryable
+-- ones that used up every attempt, broken down by cause
+CREATE OR ALTER VIEW DeadPageReasons AS
+SELECT
+    p.CollectionId,
+    CASE WHEN p.FailureClass = N'permanent' THEN N'permanent' ELSE N'max_attempts' END AS DeadReason,
+    ISNULL(p.FailureCause, N'unknown') AS FailureCause,
+    COUNT(*) AS Pages,
+    MAX(p.LastError) AS SampleError,
+    MAX(p.UpdatedAt) AS LastFailedAt
+FROM Pages p
+WHERE p.Progress = N'dead'
+GROUP BY p.CollectionId,
+    CASE WHEN p.FailureClass = N'permanent' THEN N'permanent' ELSE N'max_attempts' END,
+    ISNULL(p.FailureCause, N'unknown');
+GO
+
+-- Page attempts per collection per day by outcome and cause, for spotting
+-- when and where failures cluster
+CREATE OR ALTER VIEW PageAttemptStats AS
+SELECT
+    a.CollectionId,
+    CAST(a.FinishedAt AS DATE) AS Day,
+    a.Outcome,
+    ISNULL(a.FailureCause, N'') AS FailureCause,
+    COUNT(*) AS Attempts,
+    AVG(a.LatencyMs) AS AvgLatencyMs,
+    MAX(a.LatencyMs) AS MaxLatencyMs,
+    SUM(ISNULL(a.RecordCount, 0)) AS Records
+FROM PageAttempts a
+GROUP BY a.CollectionId, CAST(a.FinishedAt AS DATE), a.Outcome, ISNULL(a.FailureCause, N'');
+GO